@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+	flag "github.com/spf13/pflag"
+)
+
+// parseCommandLine разбирает флаги и один раз вычисляет производные поля
+// конфигурации, в том числе читает --random-source. При ошибке завершает
+// программу с кодом 2, как GNU sort.
+func parseCommandLine() (*config.SortConfig, []string) {
+	cfg := config.SortConfig{}
+
+	flag.StringArrayVarP(&cfg.KeySpecs, "key", "k", nil, "ключ сортировки POS1[,POS2] с модификаторами (можно указывать несколько раз)")
+	flag.BoolVarP(&cfg.NumericSort, "numeric", "n", false, "числовая сортировка")
+	flag.BoolVarP(&cfg.GeneralSort, "general-numeric-sort", "g", false, "сортировка по числам с плавающей точкой, включая 1e3, inf и nan")
+	flag.BoolVarP(&cfg.VersionSort, "version-sort", "V", false, "естественный порядок номеров версий (v1.2.9 < v1.2.10)")
+	flag.BoolVarP(&cfg.RandomSort, "random-sort", "R", false, "перемешать строки, группируя одинаковые ключи")
+	flag.StringVar(&cfg.RandomSource, "random-source", "", "взять случайные байты для -R из файла")
+	flag.StringVar(&cfg.Seed, "seed", "", "зерно для воспроизводимого -R")
+	flag.BoolVarP(&cfg.ReverseOrder, "reverse", "r", false, "обратный порядок")
+	flag.BoolVarP(&cfg.UniqueOnly, "unique", "u", false, "только уникальные строки")
+	flag.BoolVar(&cfg.Count, "count", false, "выводить каждую строку с равным ключом один раз с числом повторений, как uniq -c")
+	flag.BoolVarP(&cfg.MonthSort, "month", "M", false, "сортировка по месяцам")
+	flag.BoolVarP(&cfg.IgnoreSpaces, "blanks", "b", false, "игнорировать пробелы")
+	flag.BoolVarP(&cfg.CheckSorted, "check", "c", false, "проверить сортировку и сообщить о первом нарушении")
+	flag.BoolVarP(&cfg.CheckQuiet, "check-quiet", "C", false, "проверить сортировку, сообщив результат только кодом возврата")
+	flag.BoolVarP(&cfg.StableSort, "stable", "s", false, "стабильная сортировка: не сравнивать строки целиком при равных ключах")
+	flag.BoolVarP(&cfg.MergeOnly, "merge", "m", false, "слить уже отсортированные файлы без пересортировки")
+	flag.BoolVarP(&cfg.HumanReadable, "human", "h", false, "человекочитаемые размеры")
+	flag.BoolVarP(&cfg.FoldCase, "ignore-case", "f", false, "не различать регистр")
+	flag.BoolVarP(&cfg.Dictionary, "dictionary-order", "d", false, "учитывать только пробелы, буквы и цифры")
+	flag.BoolVarP(&cfg.PrintableOnly, "ignore-nonprinting", "i", false, "игнорировать непечатаемые символы")
+	flag.BoolVar(&cfg.Collate, "collate", false, "сопоставление по Unicode Collation Algorithm с правилами русской локали: ё рядом с е, é рядом с e, диакритика и регистр учитываются во вторую очередь")
+	flag.StringVarP(&cfg.FieldSep, "field-separator", "t", "", "разделитель полей вместо перехода от пробелов к непробельным символам")
+	flag.StringVarP(&cfg.BufferSize, "buffer-size", "S", "", "объём памяти под блоки, например 512M (суффиксы b, K, M, G, T)")
+	flag.BoolVarP(&cfg.ZeroTerminated, "zero-terminated", "z", false, "записи разделяются нулевым байтом, а не переводом строки")
+	flag.StringVarP(&cfg.OutputFile, "output", "o", "", "записать результат в файл (можно указать один из входных файлов)")
+	flag.StringVar(&cfg.Files0From, "files0-from", "", "взять имена входных файлов, разделённые нулевым байтом, из файла (- — stdin)")
+	flag.StringVarP(&cfg.TempDir, "temporary-directory", "T", "", "каталог для временных файлов (по умолчанию $TMPDIR или /tmp)")
+	flag.BoolVar(&cfg.CompressTemp, "compress-temp", false, "сжимать временные файлы gzip: меньше места на диске ценой скорости")
+	flag.IntVar(&cfg.BatchSize, "batch-size", config.DefaultMergeBatch, "сливать не больше N временных файлов одновременно")
+	flag.BoolVar(&cfg.Debug, "debug", false, "подчеркнуть под каждой строкой часть, использованную как ключ сортировки")
+	flag.IntVar(&cfg.Parallel, "parallel", 0, "число параллельно сортируемых блоков (по умолчанию по числу CPU, не больше 8)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Использование: %s [опции] [файл...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Опции:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if err := cfg.Prepare(); err != nil {
+		fmt.Fprintf(os.Stderr, "sort: %v\n", err)
+		os.Exit(2)
+	}
+	return &cfg, flag.Args()
+}
@@ -1,35 +1,56 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/GkadyrG/L2/L2.10/internal/processor"
+	"github.com/GkadyrG/L2/L2.10/internal/tempfiles"
 	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+	"github.com/GkadyrG/L2/L2.10/pkg/extsort"
 )
 
+// interruptGrace — сколько ждать, пока отменённая сортировка сама удалит
+// временные файлы, прежде чем удалить их принудительно.
+const interruptGrace = 2 * time.Second
+
 func main() {
-	settings, inputFiles := config.ParseCommandLine()
+	settings, inputFiles := parseCommandLine()
+
+	// При прерывании отменяем сортировку: она удаляет временные блоки сама.
+	// Если она заблокирована на чтении ввода, удаляем их принудительно,
+	// иначе они остаются в каталоге -T
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupts
+		cancel()
+		time.Sleep(interruptGrace)
+		tempfiles.RemoveAll()
+		os.Exit(130)
+	}()
 
-	switch len(inputFiles) {
-	case 1:
-		// Обработка одного файла
-		if err := processor.ProcessFileToConsole(inputFiles[0], *settings); err != nil {
-			log.Fatal(err)
+	if err := run(ctx, settings, inputFiles); err != nil {
+		tempfiles.RemoveAll()
+		if ctx.Err() != nil {
+			os.Exit(130)
 		}
-	case 0:
-		// Определяем источник данных
-		fileInfo, _ := os.Stdin.Stat()
-		if (fileInfo.Mode() & os.ModeCharDevice) == 0 {
-			// Данные из pipe
-			if err := processor.ProcessPipeInput(*settings); err != nil {
-				log.Fatal(err)
-			}
-		} else {
-			// Интерактивный ввод
-			processor.ProcessInteractiveMode(*settings)
+		if errors.Is(err, extsort.ErrDisorder) {
+			// Нарушение порядка уже описано проверкой (или скрыто с -C)
+			os.Exit(1)
 		}
-	default:
-		log.Fatal("Слишком много входных файлов")
+		fmt.Fprintf(os.Stderr, "sort: %v\n", err)
+		os.Exit(2)
 	}
 }
+
+func run(ctx context.Context, settings *config.SortConfig, inputFiles []string) error {
+	return processor.Process(ctx, inputFiles, *settings)
+}
@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GkadyrG/L2/L2.10/internal/sorter"
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// inputNames возвращает список входов: операнды командной строки или,
+// с --files0-from, имена из указанного файла. Без операндов читается stdin.
+func inputNames(args []string, cfg config.SortConfig) ([]string, error) {
+	if cfg.Files0From == "" {
+		if len(args) == 0 {
+			return []string{sorter.StdinName}, nil
+		}
+		return args, nil
+	}
+
+	if len(args) > 0 {
+		return nil, fmt.Errorf("лишний операнд %q: при --files0-from имена файлов берутся только из %s", args[0], cfg.Files0From)
+	}
+	return readFiles0From(cfg.Files0From, os.Stdin)
+}
+
+// readFiles0From читает имена файлов, разделённые нулевым байтом, как GNU
+// sort: пустые имена запрещены, а при чтении списка из stdin запрещено и
+// имя "-".
+func readFiles0From(source string, stdin io.Reader) ([]string, error) {
+	var content []byte
+	var err error
+	if source == sorter.StdinName {
+		content, err = io.ReadAll(stdin)
+	} else {
+		content, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать список файлов %s: %w", source, err)
+	}
+
+	content = bytes.TrimSuffix(content, []byte{0})
+	if len(content) == 0 {
+		return nil, fmt.Errorf("нет входных файлов в %s", source)
+	}
+
+	var names []string
+	for i, name := range bytes.Split(content, []byte{0}) {
+		switch {
+		case len(name) == 0:
+			return nil, fmt.Errorf("%s:%d: недопустимое пустое имя файла", source, i+1)
+		case source == sorter.StdinName && string(name) == sorter.StdinName:
+			return nil, fmt.Errorf("%s:%d: при чтении списка из stdin имя '-' недопустимо", source, i+1)
+		}
+		names = append(names, string(name))
+	}
+	return names, nil
+}
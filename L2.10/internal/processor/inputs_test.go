@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+func TestInputNames(t *testing.T) {
+	if got := mustInputNames(t, nil, config.SortConfig{}); !slices.Equal(got, []string{"-"}) {
+		t.Errorf("no operands: got %q, want stdin", got)
+	}
+	args := []string{"a.txt", "-", "b.gz"}
+	if got := mustInputNames(t, args, config.SortConfig{}); !slices.Equal(got, args) {
+		t.Errorf("operands: got %q, want %q", got, args)
+	}
+
+	list := filepath.Join(t.TempDir(), "list")
+	if err := os.WriteFile(list, []byte("a.txt\x00dir/b c.gz\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.SortConfig{Files0From: list}
+	if got, want := mustInputNames(t, nil, cfg), []string{"a.txt", "dir/b c.gz"}; !slices.Equal(got, want) {
+		t.Errorf("files0-from: got %q, want %q", got, want)
+	}
+	if _, err := inputNames([]string{"extra"}, cfg); err == nil {
+		t.Error("expected error for operand together with --files0-from")
+	}
+}
+
+func mustInputNames(t *testing.T, args []string, cfg config.SortConfig) []string {
+	t.Helper()
+	names, err := inputNames(args, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestReadFiles0FromErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		content string
+	}{
+		{"empty list", "list", ""},
+		{"empty name", "list", "a\x00\x00b"},
+		{"stdin name from stdin", "-", "a\x00-\x00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			if source != "-" {
+				source = filepath.Join(t.TempDir(), source)
+				if err := os.WriteFile(source, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := readFiles0From(source, strings.NewReader(tt.content)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/GkadyrG/L2/L2.10/internal/tempfiles"
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// maxSymlinks ограничивает цепочку висячих символических ссылок в пути -o.
+const maxSymlinks = 40
+
+// sortOutput — назначение результата: stdout или файл -o. Результат сначала
+// пишется во временный файл и попадает в -o только после успешной сортировки,
+// поэтому -o может совпадать с одним из входов. Обычный (или ещё не
+// существующий) файл заменяется атомарным переименованием; в устройство,
+// канал и т.п. результат копируется, а сам узел не трогается.
+type sortOutput struct {
+	writer io.Writer
+	temp   *os.File
+	path   string      // путь -o после разрешения символических ссылок
+	target os.FileInfo // существующее назначение, nil если файла ещё нет
+}
+
+func openOutput(cfg config.SortConfig) (*sortOutput, error) {
+	if cfg.OutputFile == "" || cfg.OutputFile == "-" {
+		return &sortOutput{writer: os.Stdout}, nil
+	}
+
+	path, target, err := resolveOutput(cfg.OutputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var temp *os.File
+	if target != nil && !target.Mode().IsRegular() {
+		temp, err = tempfiles.CreateTemp(tempfiles.Dir(cfg.TempDir), "sort-output-*")
+	} else {
+		// Новый файл получает 0666 за вычетом umask, как в GNU sort.
+		temp, err = tempfiles.CreateTempPerm(filepath.Dir(path), "."+filepath.Base(path)+".sort-*", 0o666)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sortOutput{writer: temp, temp: temp, path: path, target: target}, nil
+}
+
+// resolveOutput разрешает символические ссылки в пути -o, чтобы заменить
+// файл, на который они указывают, а не саму ссылку. Для висячей ссылки
+// возвращается путь, который она назовёт, и nil вместо сведений о файле.
+func resolveOutput(path string) (string, os.FileInfo, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		info, err := os.Stat(resolved)
+		return resolved, info, err
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", nil, err
+	}
+
+	for range maxSymlinks {
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return path, nil, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			return path, info, nil
+		}
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", nil, err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		path = link
+	}
+	return "", nil, fmt.Errorf("слишком много символических ссылок: %s", path)
+}
+
+// commit переносит результат в файл -o. Обычный файл заменяется с
+// сохранением его прав; новый создаётся с правами временного файла.
+func (o *sortOutput) commit() error {
+	if o.temp == nil {
+		return nil
+	}
+	if o.target != nil && !o.target.Mode().IsRegular() {
+		return o.copyToTarget()
+	}
+
+	if info, err := os.Stat(o.path); err == nil {
+		if err := o.temp.Chmod(info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if err := o.temp.Close(); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", o.path, err)
+	}
+	if err := os.Rename(o.temp.Name(), o.path); err != nil {
+		return err
+	}
+	tempfiles.Forget(o.temp.Name())
+	o.temp = nil
+	return nil
+}
+
+// copyToTarget открывает назначение, которое нельзя заменить переименованием
+// (устройство, канал), и копирует в него результат. O_TRUNC применяется
+// только сейчас, когда сортировка уже удалась.
+func (o *sortOutput) copyToTarget() error {
+	if _, err := o.temp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	target, err := os.OpenFile(o.path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, o.temp); err != nil {
+		target.Close()
+		return fmt.Errorf("ошибка записи %s: %w", o.path, err)
+	}
+	if err := target.Close(); err != nil {
+		return fmt.Errorf("ошибка записи %s: %w", o.path, err)
+	}
+	o.discard()
+	return nil
+}
+
+// discard удаляет временный файл, если результат не был зафиксирован.
+func (o *sortOutput) discard() {
+	if o.temp != nil {
+		o.temp.Close()
+		tempfiles.Remove(o.temp.Name())
+		o.temp = nil
+	}
+}
+
+// writeSorted открывает назначение, передаёт его в write и фиксирует результат.
+func writeSorted(cfg config.SortConfig, write func(io.Writer) error) error {
+	output, err := openOutput(cfg)
+	if err != nil {
+		return err
+	}
+	defer output.discard()
+
+	if err := write(output.writer); err != nil {
+		return err
+	}
+	return output.commit()
+}
@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+func TestWriteSortedReplacesFileAtomically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.SortConfig{OutputFile: path}
+
+	// Ошибка сортировки не должна повредить существующий файл.
+	failure := errors.New("boom")
+	err := writeSorted(cfg, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "old\n" {
+		t.Fatalf("file changed after failure: %q", content)
+	}
+
+	err = writeSorted(cfg, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new\n")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "new\n" {
+		t.Fatalf("got %q, want %q", content, "new\n")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("permissions changed to %v", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func writeString(text string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, text)
+		return err
+	}
+}
+
+func TestWriteSortedFollowsSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink("target.txt", link); err != nil {
+		t.Fatal(err)
+	}
+	dangling := filepath.Join(dir, "dangling.txt")
+	if err := os.Symlink("created.txt", dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{link, dangling} {
+		if err := writeSorted(config.SortConfig{OutputFile: path}, writeString("new\n")); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("%s: symlink replaced by a %v", path, info.Mode().Type())
+		}
+	}
+	for _, path := range []string{target, filepath.Join(dir, "created.txt")} {
+		if content, _ := os.ReadFile(path); string(content) != "new\n" {
+			t.Errorf("%s: got %q, want %q", path, content, "new\n")
+		}
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0o600 {
+		t.Errorf("permissions changed to %v", info.Mode().Perm())
+	}
+}
+
+func TestWriteSortedNewFileHonorsUmask(t *testing.T) {
+	dir := t.TempDir()
+	// Файл, созданный с 0666, показывает, что оставляет текущая umask.
+	reference := filepath.Join(dir, "reference")
+	file, err := os.OpenFile(reference, os.O_CREATE|os.O_WRONLY, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	want, _ := os.Stat(reference)
+
+	path := filepath.Join(dir, "new.txt")
+	if err := writeSorted(config.SortConfig{OutputFile: path}, writeString("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("got permissions %v, want %v", info.Mode().Perm(), want.Mode().Perm())
+	}
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package processor
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+func TestWriteSortedKeepsSpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	fifo := filepath.Join(dir, "out.fifo")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.SortConfig{OutputFile: fifo, TempDir: dir}
+
+	// Неудачная сортировка не открывает канал: иначе читатель получил бы EOF.
+	failure := errors.New("boom")
+	if err := writeSorted(cfg, func(w io.Writer) error { return failure }); !errors.Is(err, failure) {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+
+	received := make(chan string, 1)
+	go func() {
+		content, _ := os.ReadFile(fifo)
+		received <- string(content)
+	}()
+	if err := writeSorted(cfg, writeString("a\nb\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; got != "a\nb\n" {
+		t.Errorf("got %q, want %q", got, "a\nb\n")
+	}
+
+	info, err := os.Lstat(fifo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("fifo replaced by a %v", info.Mode().Type())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
@@ -1,91 +1,112 @@
 package processor
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"slices"
 
-	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
 	"github.com/GkadyrG/L2/L2.10/internal/sorter"
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+	"github.com/GkadyrG/L2/L2.10/pkg/extsort"
 )
 
-func ProcessPipeInput(cfg config.SortConfig) error {
-	tempInput, err := os.CreateTemp("", "input_data_*.tmp")
+// Process сортирует (с -m сливает, с -c/-C проверяет) входы и выводит
+// результат в stdout или в файл -o. Входы — операнды командной строки или
+// имена из --files0-from; "-" и отсутствие операндов означают stdin. Все
+// входы читаются как один поток строк, сжатые распаковываются на лету.
+func Process(ctx context.Context, args []string, cfg config.SortConfig) error {
+	names, err := inputNames(args, cfg)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tempInput.Name())
-	defer tempInput.Close()
-
-	if _, err = io.Copy(tempInput, os.Stdin); err != nil {
-		return err
+	if slices.Contains(names, sorter.StdinName) && isTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Введите текст для сортировки (Ctrl+D для завершения):")
 	}
-	tempInput.Close()
 
-	tempOutput, err := os.CreateTemp("", "output_data_*.tmp")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tempOutput.Name())
-	defer tempOutput.Close()
-
-	if err = sorter.ExecuteExternalSort(tempInput.Name(), tempOutput.Name(), cfg); err != nil {
-		return err
-	}
-
-	if _, err = tempOutput.Seek(0, 0); err != nil {
-		return err
+	if isCheckMode(cfg) {
+		return checkInput(ctx, names, cfg)
 	}
+	return writeSorted(cfg, withDebug(cfg, func(output io.Writer) error {
+		return extsort.SortFiles(ctx, names, output, options(cfg))
+	}))
+}
 
-	_, err = io.Copy(os.Stdout, tempOutput)
-	return err
+func isCheckMode(cfg config.SortConfig) bool {
+	return cfg.CheckSorted || cfg.CheckQuiet
 }
 
-func ProcessFileToConsole(inputPath string, cfg config.SortConfig) error {
-	tempOutput, err := os.CreateTemp("", "file_output_*.tmp")
-	if err != nil {
-		return err
+// options переносит на extsort.Options уже вычисленные настройки утилиты.
+// Соль -R передаётся готовой, поэтому --random-source читается только
+// один раз, при разборе командной строки.
+func options(cfg config.SortConfig) extsort.Options {
+	return extsort.Options{
+		Keys:              cfg.KeySpecs,
+		Numeric:           cfg.NumericSort,
+		GeneralNumeric:    cfg.GeneralSort,
+		HumanNumeric:      cfg.HumanReadable,
+		Month:             cfg.MonthSort,
+		Version:           cfg.VersionSort,
+		Random:            cfg.RandomSort,
+		Reverse:           cfg.ReverseOrder,
+		IgnoreBlanks:      cfg.IgnoreSpaces,
+		FoldCase:          cfg.FoldCase,
+		Dictionary:        cfg.Dictionary,
+		IgnoreNonprinting: cfg.PrintableOnly,
+		Collate:           cfg.Collate,
+		Unique:            cfg.UniqueOnly,
+		Count:             cfg.Count,
+		Stable:            cfg.StableSort,
+		MergeOnly:         cfg.MergeOnly,
+		FieldSeparator:    cfg.Separator,
+		ZeroTerminated:    cfg.ZeroTerminated,
+		RandomSalt:        []byte(cfg.RandomSalt),
+		BufferSize:        cfg.BufferBytes,
+		Parallel:          cfg.Workers,
+		MergeBatch:        cfg.MergeBatch,
+		TempDir:           cfg.TempDir,
+		CompressTemp:      cfg.CompressTemp,
 	}
-	defer os.Remove(tempOutput.Name())
-	defer tempOutput.Close()
+}
 
-	if err = sorter.ExecuteExternalSort(inputPath, tempOutput.Name(), cfg); err != nil {
-		return err
+// withDebug в режиме --debug пропускает вывод write через sorter.WriteDebug,
+// который размечает ключи под каждой строкой.
+func withDebug(cfg config.SortConfig, write func(io.Writer) error) func(io.Writer) error {
+	if !cfg.Debug {
+		return write
 	}
-
-	if _, err = tempOutput.Seek(0, 0); err != nil {
+	return func(output io.Writer) error {
+		reader, writer := io.Pipe()
+		annotated := make(chan error, 1)
+		go func() {
+			err := sorter.WriteDebug(reader, output, cfg)
+			reader.CloseWithError(err) // разблокирует write, если разметка прервалась
+			annotated <- err
+		}()
+
+		err := write(writer)
+		writer.CloseWithError(err)
+		if debugErr := <-annotated; err == nil {
+			err = debugErr
+		}
 		return err
 	}
-
-	_, err = io.Copy(os.Stdout, tempOutput)
-	return err
 }
 
-func ProcessInteractiveMode(cfg config.SortConfig) {
-	reader := bufio.NewReader(os.Stdin)
-	var textLines []string
-
-	fmt.Fprintln(os.Stderr, "Введите текст для сортировки (Ctrl+D для завершения):")
-
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break
-		}
-		textLines = append(textLines, strings.TrimSuffix(line, "\n"))
+// checkInput проверяет порядок единственного входа в режиме -c/-C.
+func checkInput(ctx context.Context, names []string, cfg config.SortConfig) error {
+	if len(names) != 1 {
+		return fmt.Errorf("проверка -c принимает только один файл, получено %d", len(names))
 	}
-
-	lineProcessor := sorter.CreateLineSorter(textLines, cfg)
-	lineProcessor.PerformSort()
-
-	output := bufio.NewWriter(os.Stdout)
-	defer output.Flush()
-
-	for _, line := range lineProcessor.GetSortedLines() {
-		if _, err := output.WriteString(line + "\n"); err != nil {
-			fmt.Printf("Ошибка записи: %v", err)
-		}
+	var diagnostics io.Writer = os.Stderr
+	if cfg.CheckQuiet {
+		diagnostics = nil
 	}
+	return extsort.CheckFile(ctx, names[0], options(cfg), diagnostics)
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// sortToFile сортирует input через Process и возвращает содержимое файла -o.
+func sortToFile(t *testing.T, cfg config.SortConfig, input string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "input")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.OutputFile = filepath.Join(dir, "output")
+	if err := Process(context.Background(), []string{path}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	output, err := os.ReadFile(cfg.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+func TestProcessReadsRandomSourceOnce(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "random")
+	if err := os.WriteFile(source, []byte("0123456789abcdef0123456789abcdef"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.SortConfig{RandomSort: true, RandomSource: source}
+	if err := cfg.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	// Как у канала <(head -c 32 /dev/urandom): второй раз источник не прочитать.
+	if err := os.Remove(source); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "a\nb\nc\nd\ne\nf\n"
+	first := sortToFile(t, cfg, input)
+	if second := sortToFile(t, cfg, input); first != second {
+		t.Errorf("same random source gave different orders:\n%s\n%s", first, second)
+	}
+}
+
+func TestProcessDebug(t *testing.T) {
+	cfg := config.SortConfig{KeySpecs: []string{"2,2n"}, Count: true, Debug: true}
+	if err := cfg.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := sortToFile(t, cfg, "b 10\na 9\nc 9\n")
+	want := "      2 a 9\n          _\n      1 b 10\n          __\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package sorter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// ErrDisorder возвращается проверкой -c/-C, если вход не отсортирован.
+var ErrDisorder = errors.New("входные данные не отсортированы")
+
+// CheckSorted потоково проверяет, что input упорядочен по правилам cfg, и
+// хранит в памяти только предыдущую строку. О первом нарушении пишется в
+// diagnostics в формате GNU sort; с nil (-C) проверка молчит. С -u равные
+// соседние строки тоже считаются нарушением. Отмена ctx прерывает проверку.
+func CheckSorted(ctx context.Context, input io.Reader, name string, cfg config.SortConfig, diagnostics io.Writer) error {
+	scanner := NewConfigScanner(input, cfg)
+	if !scanner.Scan() {
+		return scanner.Err() // Пустой вход считается отсортированным
+	}
+
+	previous := newSortRecord(cfg, scanner.Text())
+	lineNumber := 1
+
+	for scanner.Scan() {
+		current := newSortRecord(cfg, scanner.Text())
+		lineNumber++
+		if lineNumber%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		result := compareRecords(cfg, &previous, &current)
+		if result > 0 || (cfg.UniqueOnly && result == 0) {
+			if diagnostics != nil {
+				fmt.Fprintf(diagnostics, "sort: %s:%d: disorder: %s\n", name, lineNumber, current.line)
+			}
+			return ErrDisorder
+		}
+		previous = current
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package sorter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCheckSorted(t *testing.T) {
+	tests := []struct {
+		name       string
+		flags      string
+		keys       []string
+		input      string
+		wantErr    bool
+		wantOutput string
+	}{
+		{"sorted", "c", nil, "a\nb\nb\nc\n", false, ""},
+		{"empty", "c", nil, "", false, ""},
+		{"disorder", "c", nil, "a\nc\nb\nd\n", true, "sort: data.txt:3: disorder: b\n"},
+		{"quiet", "C", nil, "a\nc\nb\n", true, ""},
+		{"numeric", "cn", nil, "2\n10\n9\n", true, "sort: data.txt:3: disorder: 9\n"},
+		{"reverse", "cr", nil, "c\nb\na\n", false, ""},
+		{"unique equal neighbours", "cu", nil, "a\nb\nb\n", true, "sort: data.txt:3: disorder: b\n"},
+		{"unique by key", "cu", []string{"1,1"}, "a 1\nb 1\nb 2\n", true, "sort: data.txt:3: disorder: b 2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, tt.flags, "", tt.keys...)
+			var diagnostics bytes.Buffer
+			var output io.Writer = &diagnostics
+			if cfg.CheckQuiet {
+				output = nil
+			}
+
+			err := CheckSorted(context.Background(), strings.NewReader(tt.input), "data.txt", cfg, output)
+			if tt.wantErr != errors.Is(err, ErrDisorder) {
+				t.Fatalf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if diagnostics.String() != tt.wantOutput {
+				t.Errorf("got diagnostics %q, want %q", diagnostics.String(), tt.wantOutput)
+			}
+		})
+	}
+}
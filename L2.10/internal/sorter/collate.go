@@ -0,0 +1,62 @@
+package sorter
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// textKey применяет к текстовому ключу модификаторы d, i и f и, если
+// включено --collate, превращает его в ключ сопоставления.
+func textKey(key config.SortKey, text string) string {
+	if key.Dictionary || key.PrintableOnly {
+		text = strings.Map(func(r rune) rune {
+			if key.Dictionary && !(unicode.IsSpace(r) || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return -1
+			}
+			if key.PrintableOnly && !unicode.IsPrint(r) {
+				return -1
+			}
+			return r
+		}, text)
+	}
+	if key.FoldCase {
+		text = strings.ToUpper(text)
+	}
+	if key.Collate {
+		return collationKey(text)
+	}
+	return text
+}
+
+// collators — пул сопоставителей для русской локали. collate.Collator нельзя
+// использовать из нескольких горутин одновременно, а блоки сортируются
+// параллельно.
+var collators = sync.Pool{
+	New: func() any { return &collator{Collator: collate.New(language.Russian)} },
+}
+
+type collator struct {
+	*collate.Collator
+	buffer collate.Buffer
+}
+
+// collationKey строит ключ сопоставления по Unicode Collation Algorithm с
+// правилами русской локали (CLDR), который сравнивается обычным побайтовым
+// сравнением. Уровни ключа: буква без учёта диакритики и регистра, затем
+// диакритика, затем регистр (строчные раньше заглавных). Поэтому
+// "еж" < "ёж" < "Ёж" < "ель" и "cafe" < "café" < "cafz", тогда как побайтово
+// "ёж" оказался бы после "я", а "École" — после "zoo".
+func collationKey(text string) string {
+	c := collators.Get().(*collator)
+	defer collators.Put(c)
+
+	key := string(c.KeyFromString(&c.buffer, text))
+	c.buffer.Reset()
+	return key
+}
@@ -0,0 +1,77 @@
+package sorter
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTextKeyModifiers(t *testing.T) {
+	tests := []struct {
+		flags string
+		keys  []string
+		input []string
+		want  []string
+	}{
+		// -f: регистр не важен, равные ключи упорядочивает последнее средство.
+		{"f", nil, []string{"b", "B", "a", "C"}, []string{"a", "B", "b", "C"}},
+		// -d: учитываются только пробелы, буквы и цифры.
+		{"d", nil, []string{"a-c", "ab", "(aa)"}, []string{"(aa)", "ab", "a-c"}},
+		// -i: непечатаемые символы пропускаются.
+		{"i", nil, []string{"b", "\x01c", "a"}, []string{"a", "b", "\x01c"}},
+		// Модификаторы работают на уровне ключа.
+		{"", []string{"2,2f", "1,1r"}, []string{"x B", "y b", "z a"}, []string{"z a", "y b", "x B"}},
+		{"", []string{"1,1d"}, []string{"b.2", "b1", "a"}, []string{"a", "b1", "b.2"}},
+	}
+
+	for _, tt := range tests {
+		cfg := testConfig(t, tt.flags, "", tt.keys...)
+		if got := memorySort(cfg, tt.input); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("flags %q keys %v: got %q, want %q", tt.flags, tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestCollationOrder(t *testing.T) {
+	input := []string{"ель", "Ёж", "яблоко", "еж", "ёж", "Ель", "apple", "Zebra", "10", "2"}
+
+	// Побайтово "ё" и "Ё" уходят за пределы алфавита.
+	plain := memorySort(testConfig(t, "", ""), input)
+	if strings.Join(plain, " ") != "10 2 Zebra apple Ёж Ель еж ель яблоко ёж" {
+		t.Fatalf("unexpected byte order: %q", plain)
+	}
+
+	want := "10 2 apple Zebra еж ёж Ёж ель Ель яблоко"
+	if got := memorySort(testConfig(t, "L", ""), input); strings.Join(got, " ") != want {
+		t.Errorf("collate: got %q, want %q", strings.Join(got, " "), want)
+	}
+
+	// Диакритика латиницы тоже учитывается только на втором уровне.
+	accented := []string{"cafz", "café", "cafe", "École", "Ecole", "zoo"}
+	if got := memorySort(testConfig(t, "L", ""), accented); strings.Join(got, " ") != "cafe café cafz Ecole École zoo" {
+		t.Errorf("collate accents: got %q", strings.Join(got, " "))
+	}
+
+	// Сопоставление применяется и к отдельным ключам: первичный уровень решает раньше вторичного.
+	keyed := []string{"1 яма", "2 ежи", "3 ёж"}
+	cfg := testConfig(t, "L", "", "2,2")
+	if got := memorySort(cfg, keyed); strings.Join(got, "|") != "3 ёж|2 ежи|1 яма" {
+		t.Errorf("collate by key: got %q", got)
+	}
+}
+
+func BenchmarkCollateSort(b *testing.B) {
+	words := []string{"ель", "Ёж", "яблоко", "café", "École", "zoo", "apple", "ёлка"}
+	rng := rand.New(rand.NewSource(1))
+	lines := make([]string, 20000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s%d %s%d", words[rng.Intn(len(words))], rng.Intn(1000), words[rng.Intn(len(words))], rng.Intn(1000))
+	}
+	cfg := testConfig(b, "L", "")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		memorySort(cfg, lines)
+	}
+}
@@ -0,0 +1,304 @@
+package sorter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// compareKeyValues сравнивает значения ключей, полученные из generateSortKey,
+// с учётом типа ключа. Текстовые ключи сравниваются побайтово. Ключи -g
+// сравниваются по значениям, разобранным в newSortRecord (compareGeneralValues).
+func compareKeyValues(key config.SortKey, first, second string) int {
+	switch {
+	case key.Numeric:
+		return compareNumeric(first, second)
+	case key.Human:
+		return compareHumanSize(first, second)
+	case key.Version:
+		return compareVersion(first, second)
+	default:
+		return strings.Compare(first, second)
+	}
+}
+
+// numericPrefix выделяет из ключа число в формате -n: ведущие пробелы,
+// необязательный минус, цифры и дробная часть. Всё после числа отбрасывается.
+func numericPrefix(text string) string {
+	text = strings.TrimLeft(text, " \t")
+
+	end := 0
+	if end < len(text) && text[end] == '-' {
+		end++
+	}
+	for end < len(text) && isDigit(text[end]) {
+		end++
+	}
+	if end < len(text) && text[end] == '.' {
+		end++
+		for end < len(text) && isDigit(text[end]) {
+			end++
+		}
+	}
+	return text[:end]
+}
+
+// compareNumeric сравнивает числа из numericPrefix как десятичные строки,
+// поэтому точность не ограничена float64: дроби, отрицательные числа и
+// значения больше 1e20 упорядочиваются верно. Пустой ключ равен нулю.
+func compareNumeric(first, second string) int {
+	firstNegative, firstInt, firstFrac := splitDecimal(first)
+	secondNegative, secondInt, secondFrac := splitDecimal(second)
+
+	if firstNegative != secondNegative {
+		if firstNegative {
+			return -1
+		}
+		return 1
+	}
+
+	result := compareDigitStrings(firstInt, secondInt)
+	if result == 0 {
+		result = strings.Compare(firstFrac, secondFrac)
+	}
+	if firstNegative {
+		return -result
+	}
+	return result
+}
+
+// splitDecimal разбирает число на знак, целую часть без ведущих нулей и
+// дробную часть без завершающих нулей. Ноль всегда неотрицателен.
+func splitDecimal(number string) (negative bool, integer, fraction string) {
+	if strings.HasPrefix(number, "-") {
+		negative = true
+		number = number[1:]
+	}
+	integer, fraction, _ = strings.Cut(number, ".")
+	integer = strings.TrimLeft(integer, "0")
+	fraction = strings.TrimRight(fraction, "0")
+
+	if integer == "" && fraction == "" {
+		negative = false
+	}
+	return negative, integer, fraction
+}
+
+// compareDigitStrings сравнивает целые без ведущих нулей: сначала по длине.
+func compareDigitStrings(first, second string) int {
+	if len(first) != len(second) {
+		if len(first) < len(second) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(first, second)
+}
+
+// Порядок -g как в GNU sort: не числа, затем NaN, затем числа от -inf до +inf.
+const (
+	generalNotNumber = iota
+	generalNaN
+	generalNumber
+)
+
+// generalValue — ключ -g, разобранный один раз при построении записи.
+type generalValue struct {
+	class int
+	value float64
+}
+
+func compareGeneralValues(first, second generalValue) int {
+	switch {
+	case first.class != second.class:
+		return compareInts(first.class, second.class)
+	case first.class != generalNumber:
+		return 0
+	case first.value < second.value:
+		return -1
+	case first.value > second.value:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// parseGeneralNumeric разбирает самый длинный префикс, который strtod принял
+// бы за число (1.5, -2e10, 0x10, 0x1p-3, inf, nan). Префикс находится одним
+// проходом, а ParseFloat вызывается один раз.
+func parseGeneralNumeric(text string) generalValue {
+	text = strings.TrimLeft(text, " \t")
+
+	sign := 0
+	if sign < len(text) && (text[sign] == '+' || text[sign] == '-') {
+		sign++
+	}
+	rest := strings.ToLower(text[sign:min(len(text), sign+3)])
+	switch rest {
+	case "inf":
+		if text[:sign] == "-" {
+			return generalValue{generalNumber, math.Inf(-1)}
+		}
+		return generalValue{generalNumber, math.Inf(1)}
+	case "nan":
+		return generalValue{class: generalNaN}
+	}
+
+	candidate, ok := hexFloatPrefix(text, sign)
+	if !ok {
+		candidate, ok = decimalFloatPrefix(text, sign)
+	}
+	if !ok {
+		return generalValue{class: generalNotNumber}
+	}
+	value, err := strconv.ParseFloat(candidate, 64)
+	if err != nil {
+		// Переполнение даёт ±inf или 0, как и в strtod; иначе префикс не число.
+		if numErr, isNum := err.(*strconv.NumError); !isNum || numErr.Err != strconv.ErrRange {
+			return generalValue{class: generalNotNumber}
+		}
+	}
+	return generalValue{generalNumber, value}
+}
+
+// decimalFloatPrefix выделяет десятичное число: цифры, необязательная дробная
+// часть и экспонента, которая учитывается, только если в ней есть цифры.
+func decimalFloatPrefix(text string, start int) (string, bool) {
+	end, digits := scanDigits(text, start, isDigit)
+	if end < len(text) && text[end] == '.' {
+		var fraction int
+		end, fraction = scanDigits(text, end+1, isDigit)
+		digits += fraction
+	}
+	if digits == 0 {
+		return "", false
+	}
+	return text[:exponentEnd(text, end, 'e')], true
+}
+
+// hexFloatPrefix выделяет шестнадцатеричное число вида 0x1.8p3. strtod
+// принимает его и без экспоненты, а ParseFloat — нет, поэтому тогда к
+// префиксу дописывается "p0".
+func hexFloatPrefix(text string, start int) (string, bool) {
+	if start+1 >= len(text) || text[start] != '0' || text[start+1] != 'x' && text[start+1] != 'X' {
+		return "", false
+	}
+	end, digits := scanDigits(text, start+2, isHexDigit)
+	if end < len(text) && text[end] == '.' {
+		var fraction int
+		end, fraction = scanDigits(text, end+1, isHexDigit)
+		digits += fraction
+	}
+	if digits == 0 {
+		return "", false // "0x" без цифр — это число 0, за которым идёт 'x'
+	}
+	if withExponent := exponentEnd(text, end, 'p'); withExponent > end {
+		return text[:withExponent], true
+	}
+	return text[:end] + "p0", true
+}
+
+// exponentEnd возвращает конец экспоненты с маркером marker, начинающейся в
+// позиции start, или start, если полной экспоненты там нет.
+func exponentEnd(text string, start int, marker byte) int {
+	if start >= len(text) || text[start]|0x20 != marker {
+		return start
+	}
+	end := start + 1
+	if end < len(text) && (text[end] == '+' || text[end] == '-') {
+		end++
+	}
+	end, digits := scanDigits(text, end, isDigit)
+	if digits == 0 {
+		return start
+	}
+	return end
+}
+
+func scanDigits(text string, start int, digit func(byte) bool) (int, int) {
+	end := start
+	for end < len(text) && digit(text[end]) {
+		end++
+	}
+	return end, end - start
+}
+
+// compareVersion реализует естественный порядок версий: строка делится на
+// чередующиеся нецифровые и цифровые части, цифровые сравниваются как числа.
+// В нецифровых частях '~' идёт раньше всего, буквы — раньше прочих символов,
+// как в dpkg. Правило GNU sort -V для суффиксов файлов (".tar.gz" и т.п.)
+// не реализовано, поэтому порядок может отличаться от GNU на таких строках.
+func compareVersion(first, second string) int {
+	for first != "" || second != "" {
+		var firstText, secondText string
+		firstText, first = splitVersionPart(first, false)
+		secondText, second = splitVersionPart(second, false)
+		if result := compareVersionText(firstText, secondText); result != 0 {
+			return result
+		}
+
+		var firstNumber, secondNumber string
+		firstNumber, first = splitVersionPart(first, true)
+		secondNumber, second = splitVersionPart(second, true)
+		firstNumber = strings.TrimLeft(firstNumber, "0")
+		secondNumber = strings.TrimLeft(secondNumber, "0")
+		if result := compareDigitStrings(firstNumber, secondNumber); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+func splitVersionPart(text string, digits bool) (string, string) {
+	end := 0
+	for end < len(text) && isDigit(text[end]) == digits {
+		end++
+	}
+	return text[:end], text[end:]
+}
+
+func compareVersionText(first, second string) int {
+	for i := 0; i < len(first) || i < len(second); i++ {
+		firstOrder, secondOrder := versionCharOrder(first, i), versionCharOrder(second, i)
+		if firstOrder != secondOrder {
+			return compareInts(firstOrder, secondOrder)
+		}
+	}
+	return 0
+}
+
+func versionCharOrder(text string, i int) int {
+	if i >= len(text) {
+		return 0
+	}
+	c := text[i]
+	switch {
+	case c == '~':
+		return -1
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func compareInts(first, second int) int {
+	switch {
+	case first < second:
+		return -1
+	case first > second:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c|0x20 >= 'a' && c|0x20 <= 'f'
+}
@@ -0,0 +1,146 @@
+package sorter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCompareNumeric(t *testing.T) {
+	tests := []struct {
+		first, second string
+		want          int
+	}{
+		{"2", "10", -1},
+		{"1.5", "1.25", 1},
+		{"-3", "2", -1},
+		{"-3", "-20", 1},
+		{"-0.5", "-0.25", -1},
+		{"0", "-0", 0},
+		{"007", "7.000", 0},
+		{"", "0", 0},
+		{"", "-1", 1},
+		{"100000000000000000000001", "100000000000000000000000", 1},
+		{"123456789012345678901234567890", "99999999999999999999", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareNumeric(tt.first, tt.second); got != tt.want {
+			t.Errorf("compareNumeric(%q, %q) = %d, want %d", tt.first, tt.second, got, tt.want)
+		}
+	}
+}
+
+func TestNumericPrefix(t *testing.T) {
+	tests := map[string]string{
+		"  42 apples": "42",
+		"-1.5e3":      "-1.5",
+		"abc":         "",
+		"3.14.15":     "3.14",
+		"\t-7":        "-7",
+	}
+
+	for input, want := range tests {
+		if got := numericPrefix(input); got != want {
+			t.Errorf("numericPrefix(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestGeneralNumericSort(t *testing.T) {
+	cfg := testConfig(t, "g", "")
+	input := []string{"1e3", "inf", "-inf", "nan", "abc", "2.5", "-1E-2", "0x10", "999"}
+
+	got := memorySort(cfg, input)
+	want := "abc nan -inf -1E-2 2.5 0x10 999 1e3 inf"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestParseGeneralNumeric(t *testing.T) {
+	tests := []struct {
+		text  string
+		class int
+		value float64
+	}{
+		{"1.5", generalNumber, 1.5},
+		{"-2e3x", generalNumber, -2000},
+		{"+.5", generalNumber, 0.5},
+		{"5.", generalNumber, 5},
+		{"1e", generalNumber, 1},
+		{"1e+", generalNumber, 1},
+		{"7E-1abc", generalNumber, 0.7},
+		{"0x10", generalNumber, 16},
+		{"0X1p-3", generalNumber, 0.125},
+		{"0x.8", generalNumber, 0.5},
+		{"0xg", generalNumber, 0},
+		{"0x1pz", generalNumber, 1},
+		{"1e999", generalNumber, math.Inf(1)},
+		{"-INFINITY", generalNumber, math.Inf(-1)},
+		{"inf", generalNumber, math.Inf(1)},
+		{"NaN", generalNaN, 0},
+		{"-nan", generalNaN, 0},
+		{"abc", generalNotNumber, 0},
+		{".", generalNotNumber, 0},
+		{"-", generalNotNumber, 0},
+		{"in", generalNotNumber, 0},
+		{"", generalNotNumber, 0},
+	}
+
+	for _, tt := range tests {
+		got := parseGeneralNumeric(tt.text)
+		if got.class != tt.class || got.value != tt.value {
+			t.Errorf("parseGeneralNumeric(%q) = %+v, want {class:%d value:%v}", tt.text, got, tt.class, tt.value)
+		}
+	}
+}
+
+// Разбор -g должен быть линейным: раньше ParseFloat вызывался на каждом
+// префиксе, и длинные нечисловые ключи сортировались квадратичное время.
+func BenchmarkGeneralNumericLongTokens(b *testing.B) {
+	lines := make([]string, 200)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d%s", i, strings.Repeat("x", 64<<10))
+	}
+	cfg := testConfig(b, "g", "")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		memorySort(cfg, lines)
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		first, second string
+		want          int
+	}{
+		{"v1.2.9", "v1.2.10", -1},
+		{"1.10", "1.9", 1},
+		{"1.0", "1.0", 0},
+		{"1.01", "1.1", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0a", "1.0+", -1},
+		{"2.0", "10.0", -1},
+		{"release-2", "release-10", -1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersion(tt.first, tt.second); got != tt.want {
+			t.Errorf("compareVersion(%q, %q) = %d, want %d", tt.first, tt.second, got, tt.want)
+		}
+	}
+}
+
+func TestVersionSortTags(t *testing.T) {
+	cfg := testConfig(t, "V", "")
+	input := []string{"v1.10.0", "v1.2.10", "v1.2.9", "v1.2.9-rc1", "v0.9"}
+
+	got := memorySort(cfg, input)
+	want := "v0.9 v1.2.9 v1.2.9-rc1 v1.2.10 v1.10.0"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
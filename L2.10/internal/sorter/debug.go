@@ -0,0 +1,121 @@
+package sorter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// WriteDebug размечает ключами каждую запись уже отсортированного вывода
+// из input (режим --debug утилиты) и пишет результат в output. Со --count
+// счётчик в начале записи остаётся префиксом, разметка сдвигается на него.
+func WriteDebug(input io.Reader, output io.Writer, cfg config.SortConfig) error {
+	scanner := NewConfigScanner(input, cfg)
+	writer := NewConfigWriter(output, cfg)
+	for scanner.Scan() {
+		prefix, line := splitCountPrefix(cfg, scanner.Text())
+		if err := writeDebugRecord(writer, cfg, prefix, line); err != nil {
+			return fmt.Errorf("ошибка записи: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("ошибка записи: %w", err)
+	}
+	return nil
+}
+
+// splitCountPrefix отделяет счётчик "%7d " (--count) от строки.
+func splitCountPrefix(cfg config.SortConfig, record string) (string, string) {
+	if !cfg.Count {
+		return "", record
+	}
+	number := strings.TrimLeft(record, " ")
+	end := strings.IndexByte(number, ' ')
+	if end < 0 {
+		return "", record
+	}
+	end += len(record) - len(number) + 1
+	return record[:end], record[end:]
+}
+
+// writeDebugRecord выводит строку в режиме --debug, как GNU sort: табуляции
+// показываются как '>', а под строкой для каждого ключа подчёркивается
+// часть, которая участвовала в сравнении. Пустой ключ отмечается
+// "^ no match for key". Последней подчёркивается вся строка, если при
+// равных ключах она сравнивается целиком (без -s, -u и --count). Префикс
+// (счётчик --count) выводится перед строкой, разметка сдвигается на его ширину.
+func writeDebugRecord(writer *RecordWriter, cfg config.SortConfig, prefix, line string) error {
+	var debug strings.Builder
+	debug.WriteString(prefix)
+	debug.WriteString(strings.ReplaceAll(line, "\t", ">"))
+	debug.WriteByte('\n')
+
+	indent := strings.Repeat(" ", utf8.RuneCountInString(prefix))
+	if !isDefaultKey(cfg) {
+		for _, key := range cfg.Keys {
+			start, end := debugKeySpan(cfg, key, line)
+			markKey(&debug, indent, line, start, end)
+		}
+	}
+	if isDefaultKey(cfg) || !(cfg.StableSort || cfg.GroupsByKey()) {
+		markKey(&debug, indent, line, 0, len(line))
+	}
+
+	return writer.WriteString(debug.String())
+}
+
+// isDefaultKey сообщает, что ключ — вся строка без модификаторов, и
+// сравнение по ключу совпадает со сравнением строки целиком.
+func isDefaultKey(cfg config.SortConfig) bool {
+	if len(cfg.KeySpecs) > 0 || len(cfg.Keys) != 1 {
+		return false
+	}
+	key := cfg.Keys[0]
+	key.Collate = false
+	return key == config.SortKey{StartField: 1, StartChar: 1}
+}
+
+// debugKeySpan возвращает байтовые границы части ключа, использованной
+// при сравнении: для числовых ключей и месяцев — только само значение.
+func debugKeySpan(cfg config.SortConfig, key config.SortKey, line string) (int, int) {
+	start, end := keyBounds(line, key, cfg.Separator)
+	text := line[start:end]
+
+	switch {
+	case key.Numeric:
+		start = skipBlanks(line, start)
+		number := numericPrefix(text)
+		if strings.Trim(number, "-.") == "" {
+			return start, start
+		}
+		return start, start + len(number)
+	case key.Human, key.General, key.Month:
+		token := firstToken(text)
+		if key.Month && parseMonthValue(token) == "0" {
+			token = ""
+		}
+		start += strings.Index(text, token)
+		return start, start + len(token)
+	default:
+		return start, end
+	}
+}
+
+// markKey дописывает строку подчёркивания ключа line[start:end] после
+// отступа indent; отступы и ширина считаются в символах.
+func markKey(debug *strings.Builder, indent, line string, start, end int) {
+	debug.WriteString(indent)
+	debug.WriteString(strings.Repeat(" ", utf8.RuneCountInString(line[:start])))
+	if start == end {
+		debug.WriteString("^ no match for key\n")
+		return
+	}
+	debug.WriteString(strings.Repeat("_", utf8.RuneCountInString(line[start:end])))
+	debug.WriteByte('\n')
+}
@@ -0,0 +1,56 @@
+package sorter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDebugRecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags string
+		keys  []string
+		line  string
+		want  string
+	}{
+		{"whole line", "", nil, "a\tb", "a>b\n___\n"},
+		{"numeric key", "", []string{"2n"}, "x  42kg", "x  42kg\n   __\n_______\n"},
+		{"numeric key missing", "", []string{"2n"}, "x", "x\n ^ no match for key\n_\n"},
+		{"stable skips last resort", "s", []string{"1,1"}, "ab cd", "ab cd\n__\n"},
+		{"month", "M", nil, " Feb 1", " Feb 1\n ___\n______\n"},
+		{"unknown month", "M", nil, "foo", "foo\n^ no match for key\n___\n"},
+		{"runes", "", []string{"2"}, "ёж ещё", "ёж ещё\n  ____\n______\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, tt.flags, "", tt.keys...)
+			var output bytes.Buffer
+			writer := NewConfigWriter(&output, cfg)
+			if err := writeDebugRecord(writer, cfg, "", tt.line); err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if output.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", output.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDebugCountPrefix(t *testing.T) {
+	cfg := testConfig(t, "", "", "2,2")
+	cfg.Count = true
+
+	var output bytes.Buffer
+	if err := WriteDebug(strings.NewReader("      2 a x\n 12345678 b y\n"), &output, cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := "      2 a x\n         __\n 12345678 b y\n           __\n"
+	if output.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", output.String(), want)
+	}
+}
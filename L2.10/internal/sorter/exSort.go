@@ -1,40 +1,71 @@
 package sorter
 
 import (
-	"bufio"
-	"container/heap"
+	"context"
 	"fmt"
-	"os"
+	"io"
+	"path/filepath"
 	"strconv"
+	"sync"
 
+	"github.com/GkadyrG/L2/L2.10/internal/tempfiles"
 	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
 )
 
-const DefaultChunkLimit = 8000 // Размер блока данных
+// lineOverhead — примерная стоимость хранения строки в блоке помимо её байтов
+// (заголовок строки и ячейка среза).
+const lineOverhead = 32
 
 type ExternalSorter struct {
-	config         config.SortConfig
-	temporaryFiles []string
-	sourceFile     string
-	targetFile     string
-	blockSize      int
+	config     config.SortConfig
+	ctx        context.Context
+	progress   func(Progress) // может быть nil
+	runs       []Input        // отсортированные прогоны, ожидающие слияния
+	sources    []Input
+	output     io.Writer
+	bufferSize int // общий бюджет памяти на блоки в байтах
+	workers    int
+	batchSize  int                                 // максимальное число одновременно сливаемых прогонов
+	compare    func(first, second *sortRecord) int // тот же компаратор, что и у LineSorter
+	workDir    string                              // уникальный для запуска каталог с блоками
+	mergedRuns int                                 // счётчик промежуточных прогонов для их имён
 }
 
-func NewExternalSorter(cfg config.SortConfig, source, target string, blockSize int) *ExternalSorter {
-	return &ExternalSorter{cfg, make([]string, 0), source, target, blockSize}
+func NewExternalSorter(cfg config.SortConfig, sources []Input, output io.Writer, bufferSize int) *ExternalSorter {
+	compare := func(first, second *sortRecord) int {
+		return compareRecords(cfg, first, second)
+	}
+	workers := max(cfg.Workers, 1)
+	return &ExternalSorter{
+		config:     cfg,
+		ctx:        context.Background(),
+		sources:    sources,
+		output:     output,
+		bufferSize: bufferSize,
+		workers:    workers,
+		batchSize:  max(cfg.MergeBatch, 2),
+		compare:    compare,
+	}
 }
 
-func ExecuteExternalSort(inputPath, outputPath string, cfg config.SortConfig) error {
-	sorter := NewExternalSorter(cfg, inputPath, outputPath, DefaultChunkLimit)
+// ExecuteExternalSort сортирует содержимое inputs как один поток строк и
+// пишет результат в output. В режиме -m входы считаются уже отсортированными
+// и только сливаются. Отмена ctx прерывает сортировку с ошибкой ctx.Err(),
+// временные файлы при этом удаляются; progress, если задан, вызывается из
+// той же горутины, что и ExecuteExternalSort.
+func ExecuteExternalSort(ctx context.Context, inputs []Input, output io.Writer, cfg config.SortConfig, progress func(Progress)) error {
+	sorter := NewExternalSorter(cfg, inputs, output, cfg.BufferBytes)
+	sorter.ctx = ctx
+	sorter.progress = progress
+
+	if err := sorter.createWorkDir(); err != nil {
+		return err
+	}
+	defer sorter.removeWorkDir()
 
-	if *sorter.config.CheckSorted {
-		if isFileSorted(sorter.sourceFile, cfg) {
-			fmt.Println("Файл уже отсортирован")
-			return nil
-		} else {
-			fmt.Println("Файл не отсортирован")
-			return nil
-		}
+	if cfg.MergeOnly {
+		sorter.runs = append(sorter.runs, inputs...)
+		return sorter.combineBlocks()
 	}
 
 	if err := sorter.divideAndSortBlocks(); err != nil {
@@ -43,126 +74,148 @@ func ExecuteExternalSort(inputPath, outputPath string, cfg config.SortConfig) er
 	return sorter.combineBlocks()
 }
 
-func (es *ExternalSorter) divideAndSortBlocks() error {
-	file, err := os.Open(es.sourceFile)
+func (es *ExternalSorter) createWorkDir() error {
+	dir, err := tempfiles.MkdirTemp(tempfiles.Dir(es.config.TempDir), "sort-*")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	es.workDir = dir
+	return nil
+}
+
+func (es *ExternalSorter) removeWorkDir() {
+	if es.workDir != "" {
+		tempfiles.Remove(es.workDir)
+		es.workDir = ""
+	}
+}
 
-	scanner := bufio.NewScanner(file)
-	dataBuffer := make([]string, 0, es.blockSize)
+// divideAndSortBlocks читает входы блоками и отдаёт их пулу воркеров.
+// Бюджет памяти делится между воркерами: в памяти одновременно находится не
+// больше workers блоков, включая заполняемый, поэтому чтение ждёт свободного слота.
+func (es *ExternalSorter) divideAndSortBlocks() error {
+	blockBudget := max(es.bufferSize/es.workers, 1)
+	slots := make(chan struct{}, es.workers)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var blockErr error
+
+	var records int64
+	dispatch := func(blockNum int, lines []string) {
+		blockFileName := filepath.Join(es.workDir, "block_"+strconv.Itoa(blockNum)+".tmp")
+		es.runs = append(es.runs, es.runInput(blockFileName))
+		es.report(Progress{Stage: StageSort, Records: records, Runs: len(es.runs)})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := es.ctx.Err(); err != nil {
+				errOnce.Do(func() { blockErr = err })
+				return
+			}
+			if err := es.processSingleBlock(blockFileName, lines); err != nil {
+				errOnce.Do(func() { blockErr = err })
+			}
+		}()
+	}
+
+	var dataBuffer []string
 	currentSize := 0
 	blockIndex := 0
 
-	for scanner.Scan() {
-		dataBuffer = append(dataBuffer, scanner.Text())
-		currentSize++
+	readSource := func(source Input) error {
+		reader, err := source.Open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		scanner := NewConfigScanner(reader, es.config)
+		for scanner.Scan() {
+			line := scanner.Text()
+			dataBuffer = append(dataBuffer, line)
+			currentSize += len(line) + lineOverhead
+			records++
+			if records%cancelCheckInterval == 0 {
+				if err := es.ctx.Err(); err != nil {
+					return err
+				}
+			}
 
-		if currentSize >= es.blockSize {
-			es.processSingleBlock(blockIndex, dataBuffer)
-			blockIndex++
-			dataBuffer = dataBuffer[:0]
-			currentSize = 0
+			if currentSize >= blockBudget {
+				dispatch(blockIndex, dataBuffer)
+				blockIndex++
+				dataBuffer = nil
+				currentSize = 0
+				slots <- struct{}{}
+			}
 		}
+		return scanner.Err()
 	}
 
-	if len(dataBuffer) > 0 {
-		es.processSingleBlock(blockIndex, dataBuffer)
+	slots <- struct{}{}
+	var readErr error
+	for _, source := range es.sources {
+		if readErr = readSource(source); readErr != nil {
+			break
+		}
 	}
 
-	return nil
+	if len(dataBuffer) > 0 && readErr == nil {
+		dispatch(blockIndex, dataBuffer)
+	} else {
+		<-slots
+	}
+	wg.Wait()
+
+	if readErr != nil {
+		return readErr
+	}
+	return blockErr
 }
 
-func (es *ExternalSorter) processSingleBlock(blockNum int, lines []string) {
+func (es *ExternalSorter) processSingleBlock(blockFileName string, lines []string) error {
 	lineSorter := CreateLineSorter(lines, es.config)
 	lineSorter.PerformSort()
 
-	blockFileName := "temp_block_" + strconv.Itoa(blockNum) + ".tmp"
-	blockFile, _ := os.Create(blockFileName)
+	blockFile, err := es.createRun(blockFileName)
+	if err != nil {
+		return fmt.Errorf("не удалось создать блок: %w", err)
+	}
 	defer blockFile.Close()
+	writer := NewConfigWriter(blockFile, es.config)
 
 	for _, line := range lineSorter.GetSortedLines() {
-		if _, err := blockFile.WriteString(line + "\n"); err != nil {
-			fmt.Printf("Ошибка записи в блок: %v", err)
+		if err := writer.Write(line); err != nil {
+			return fmt.Errorf("ошибка записи в блок: %w", err)
 		}
 	}
-
-	es.temporaryFiles = append(es.temporaryFiles, blockFileName)
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("ошибка записи в блок: %w", err)
+	}
+	return blockFile.Close()
 }
 
+// combineBlocks сливает блоки в выходной поток. Если блоков больше, чем
+// batchSize, они сначала каскадно сливаются в промежуточные прогоны, чтобы
+// не открывать одновременно больше batchSize файлов.
 func (es *ExternalSorter) combineBlocks() error {
-	outputFile, err := os.Create(es.targetFile)
-	if err != nil {
-		return err
-	}
-	writer := bufio.NewWriter(outputFile)
-	defer func() {
-		writer.Flush()
-		outputFile.Close()
-	}()
-
-	blockFiles := make([]*os.File, len(es.temporaryFiles))
-	blockScanners := make([]*bufio.Scanner, len(es.temporaryFiles))
-
-	for i, tempFile := range es.temporaryFiles {
-		f, err := os.Open(tempFile)
+	runs := es.runs
+	for len(runs) > es.batchSize {
+		merged, err := es.mergeLevel(runs)
 		if err != nil {
 			return err
 		}
-		blockFiles[i] = f
-		blockScanners[i] = bufio.NewScanner(f)
+		runs = merged
+		es.report(Progress{Stage: StageMerge, Runs: len(runs)})
 	}
 
-	priorityQueue := &MinimalHeap{}
-	heap.Init(priorityQueue)
-
-	for i, scanner := range blockScanners {
-		if scanner.Scan() {
-			heap.Push(priorityQueue, QueueElement{content: scanner.Text(), sourceIndex: i})
-		}
-	}
-
-	var previousLine string
-	isFirstLine := true
-
-	for priorityQueue.Len() > 0 {
-		currentElement := heap.Pop(priorityQueue).(QueueElement)
-
-		if *es.config.UniqueOnly {
-			if isFirstLine {
-				previousLine = currentElement.content
-				if _, err := writer.WriteString(currentElement.content + "\n"); err != nil {
-					fmt.Printf("Ошибка записи: %v", err)
-				}
-				isFirstLine = false
-			} else if generateSortKey(es.config, currentElement.content) != generateSortKey(es.config, previousLine) {
-				previousLine = currentElement.content
-				if _, err := writer.WriteString(currentElement.content + "\n"); err != nil {
-					fmt.Printf("Ошибка записи: %v", err)
-				}
-			}
-		} else {
-			if _, err := writer.WriteString(currentElement.content + "\n"); err != nil {
-				fmt.Printf("Ошибка записи: %v", err)
-			}
-		}
-
-		if blockScanners[currentElement.sourceIndex].Scan() {
-			nextContent := blockScanners[currentElement.sourceIndex].Text()
-			if !(*es.config.UniqueOnly && nextContent == previousLine) {
-				heap.Push(priorityQueue, QueueElement{
-					content:     nextContent,
-					sourceIndex: currentElement.sourceIndex,
-				})
-			}
-		}
-	}
+	return es.mergeRuns(runs, es.output, true)
+}
 
-	for _, f := range blockFiles {
-		f.Close()
-		os.Remove(f.Name())
+func (es *ExternalSorter) report(progress Progress) {
+	if es.progress != nil {
+		es.progress(progress)
 	}
-
-	return nil
 }
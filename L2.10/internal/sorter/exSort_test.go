@@ -0,0 +1,523 @@
+package sorter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// testConfig собирает SortConfig так же, как разбор командной строки:
+// flags — глобальные флаги в виде букв ("nr", "M"; L — --collate), keySpecs — значения -k.
+func testConfig(t testing.TB, flags, separator string, keySpecs ...string) config.SortConfig {
+	t.Helper()
+
+	has := func(flag rune) bool {
+		return strings.ContainsRune(flags, flag)
+	}
+	cfg := config.SortConfig{
+		KeySpecs:       keySpecs,
+		NumericSort:    has('n'),
+		GeneralSort:    has('g'),
+		VersionSort:    has('V'),
+		RandomSort:     has('R'),
+		Seed:           "test",
+		ReverseOrder:   has('r'),
+		UniqueOnly:     has('u'),
+		MonthSort:      has('M'),
+		IgnoreSpaces:   has('b'),
+		CheckSorted:    has('c'),
+		CheckQuiet:     has('C'),
+		MergeOnly:      has('m'),
+		StableSort:     has('s'),
+		ZeroTerminated: has('z'),
+		HumanReadable:  has('h'),
+		FoldCase:       has('f'),
+		Dictionary:     has('d'),
+		PrintableOnly:  has('i'),
+		Collate:        has('L'),
+		FieldSep:       separator,
+	}
+	if err := cfg.Prepare(); err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	return cfg
+}
+
+func writeLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	content := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		content += "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func externalSort(t *testing.T, cfg config.SortConfig, lines []string, bufferSize int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	writeLines(t, input, lines)
+
+	cfg.TempDir = dir
+	outputFile := createFile(t, output)
+	defer outputFile.Close()
+
+	es := NewExternalSorter(cfg, SourceInputs([]string{input}, nil), outputFile, bufferSize)
+	if err := es.createWorkDir(); err != nil {
+		t.Fatal(err)
+	}
+	defer es.removeWorkDir()
+	if err := es.divideAndSortBlocks(); err != nil {
+		t.Fatalf("divideAndSortBlocks: %v", err)
+	}
+	if err := es.combineBlocks(); err != nil {
+		t.Fatalf("combineBlocks: %v", err)
+	}
+	return readLines(t, output)
+}
+
+func createFile(t *testing.T, path string) *os.File {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// sortFiles запускает ExecuteExternalSort и пишет результат в файл output.
+func sortFiles(inputs []string, output string, cfg config.SortConfig) error {
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := ExecuteExternalSort(context.Background(), SourceInputs(inputs, nil), file, cfg, nil); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func memorySort(cfg config.SortConfig, lines []string) []string {
+	sorted := append([]string(nil), lines...)
+	lineSorter := CreateLineSorter(sorted, cfg)
+	lineSorter.PerformSort()
+	return lineSorter.GetSortedLines()
+}
+
+var (
+	testWords  = []string{"apple", "Banana", "cherry", "date", "", "яблоко", "ёж", "zeta"}
+	testMonths = []string{"jan", "Feb", "mar", "APR", "may", "june", "dec", "none"}
+	testSizes  = []string{"1K", "2M", "512", "3G", "10K", "0", "1T", "7"}
+)
+
+func randomLine(rng *rand.Rand) string {
+	fields := []string{
+		testWords[rng.Intn(len(testWords))],
+		fmt.Sprintf("%.*f", rng.Intn(3), rng.Float64()*200-50),
+		testMonths[rng.Intn(len(testMonths))],
+		testSizes[rng.Intn(len(testSizes))],
+	}
+	// Иногда обрезаем строку, чтобы проверить ключи по отсутствующим полям.
+	return strings.Join(fields[:1+rng.Intn(len(fields))], ",")
+}
+
+func TestExternalSortMatchesLineSorter(t *testing.T) {
+	configs := []struct {
+		flags string
+		keys  []string
+	}{
+		{"", nil},
+		{"r", nil},
+		{"n", []string{"2,2"}},
+		{"nr", []string{"2,2"}},
+		{"", []string{"3,3M", "1,1r"}},
+		{"", []string{"4,4h", "2,2n"}},
+		{"", []string{"4,4hr", "1.2,1.3"}},
+		{"", []string{"5,5n", "1,1"}},
+		{"", []string{"2,2nr", "3,3M", "1"}},
+		{"s", []string{"3,3M"}},
+		{"sr", []string{"2,2n"}},
+		{"fL", nil},
+		{"", []string{"1,1df", "2,2n"}},
+		{"g", []string{"2,2"}},
+		{"V", nil},
+		{"R", nil},
+		{"", []string{"3,3R", "2,2n"}},
+		{"u", nil},
+		{"u", []string{"2,2n"}},
+		{"ur", []string{"3,3M", "1,1f"}},
+	}
+
+	for _, tc := range configs {
+		name := fmt.Sprintf("flags=%q keys=%v", tc.flags, tc.keys)
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig(t, tc.flags, ",", tc.keys...)
+
+			for seed := int64(1); seed <= 20; seed++ {
+				rng := rand.New(rand.NewSource(seed))
+				lines := make([]string, rng.Intn(300))
+				for i := range lines {
+					lines[i] = randomLine(rng)
+				}
+
+				cfg.Workers = 1 + rng.Intn(4)
+				want := memorySort(cfg, lines)
+				got := externalSort(t, cfg, lines, 1+rng.Intn(40*lineOverhead))
+
+				if len(got) != len(want) {
+					t.Fatalf("seed %d: got %d lines, want %d", seed, len(got), len(want))
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("seed %d: line %d: got %q, want %q", seed, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMergeHonoursReverseAndMissingFields(t *testing.T) {
+	cfg := testConfig(t, "", "", "2,2nr")
+	lines := []string{"a 5", "b", "c 20", "d 1", "e", "f 7"}
+
+	got := externalSort(t, cfg, lines, 2*lineOverhead)
+	want := []string{"c 20", "f 7", "a 5", "d 1", "b", "e"}
+
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParallelBlocksKeepInputOrder(t *testing.T) {
+	cfg := testConfig(t, "", "")
+	cfg.Workers = 8
+
+	lines := make([]string, 500)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%05d", len(lines)-i)
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	writeLines(t, input, lines)
+
+	cfg.TempDir = dir
+	outputFile := createFile(t, filepath.Join(dir, "output.txt"))
+	defer outputFile.Close()
+
+	es := NewExternalSorter(cfg, SourceInputs([]string{input}, nil), outputFile, 8*lineOverhead)
+	if err := es.createWorkDir(); err != nil {
+		t.Fatal(err)
+	}
+	defer es.removeWorkDir()
+	if err := es.divideAndSortBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	if len(es.runs) < 2 {
+		t.Fatalf("expected several blocks, got %d", len(es.runs))
+	}
+	// Блоки нумеруются в порядке чтения, даже если воркеры завершились в другом порядке.
+	for i, run := range es.runs {
+		if want := filepath.Join(es.workDir, fmt.Sprintf("block_%d.tmp", i)); run.Name != want {
+			t.Fatalf("block %d: got %q, want %q", i, run.Name, want)
+		}
+	}
+	if err := es.combineBlocks(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readLines(t, filepath.Join(dir, "output.txt"))
+	for i := 1; i < len(got); i++ {
+		if got[i-1] > got[i] {
+			t.Fatalf("output is not sorted at line %d: %q > %q", i, got[i-1], got[i])
+		}
+	}
+}
+
+func TestExecuteExternalSortUsesAndCleansTempDir(t *testing.T) {
+	dir := t.TempDir()
+	tempDir := filepath.Join(dir, "tmp")
+	if err := os.Mkdir(tempDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t, "", "")
+	cfg.TempDir = tempDir
+	cfg.BufferBytes = 4 * lineOverhead
+
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	writeLines(t, input, []string{"d", "c", "b", "a", "e", "f", "g"})
+
+	if err := sortFiles([]string{input}, output, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(readLines(t, output), ""); got != "abcdefg" {
+		t.Errorf("got %q, want %q", got, "abcdefg")
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestExecuteExternalSortCleansUpOnError(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t, "", "")
+	cfg.TempDir = dir
+
+	input := filepath.Join(dir, "input.txt")
+	writeLines(t, input, []string{"b", "a"})
+
+	// Второй вход не существует: первый уже разбит на блоки, когда чтение упадёт.
+	missing := filepath.Join(dir, "missing.txt")
+	if err := ExecuteExternalSort(context.Background(), SourceInputs([]string{input, missing}, nil), io.Discard, cfg, nil); err == nil {
+		t.Fatal("expected error for missing input")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestCascadeMergeWithTinyBatch(t *testing.T) {
+	for _, batch := range []int{2, 3, 5} {
+		t.Run(fmt.Sprintf("batch=%d", batch), func(t *testing.T) {
+			cfg := testConfig(t, "", ",", "2,2n", "1,1r")
+			cfg.MergeBatch = batch
+
+			rng := rand.New(rand.NewSource(int64(batch)))
+			lines := make([]string, 400)
+			for i := range lines {
+				lines[i] = randomLine(rng)
+			}
+
+			want := memorySort(cfg, lines)
+			got := externalSort(t, cfg, lines, 10*lineOverhead)
+
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Fatal("cascade merge output differs from in-memory sort")
+			}
+		})
+	}
+}
+
+func TestMergeLevelReducesRuns(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t, "", "")
+	cfg.TempDir = dir
+	cfg.MergeBatch = 3
+
+	input := filepath.Join(dir, "input.txt")
+	lines := make([]string, 50)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%03d", (i*37)%50)
+	}
+	writeLines(t, input, lines)
+
+	outputFile := createFile(t, filepath.Join(dir, "output.txt"))
+	defer outputFile.Close()
+
+	es := NewExternalSorter(cfg, SourceInputs([]string{input}, nil), outputFile, 5*lineOverhead)
+	if err := es.createWorkDir(); err != nil {
+		t.Fatal(err)
+	}
+	defer es.removeWorkDir()
+	if err := es.divideAndSortBlocks(); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := es.runs
+	if len(runs) != 10 {
+		t.Fatalf("expected 10 blocks, got %d", len(runs))
+	}
+	merged, err := es.mergeLevel(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 4 {
+		t.Fatalf("expected 4 runs after one level, got %d", len(merged))
+	}
+	for _, run := range runs {
+		if _, err := os.Stat(run.Name); !os.IsNotExist(err) {
+			t.Errorf("merged run %s was not removed", run.Name)
+		}
+	}
+
+	// Второй уровень каскада сольёт оставшиеся 4 прогона в 2, затем финальное слияние.
+	es.runs = merged
+	if err := es.combineBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	got := readLines(t, filepath.Join(dir, "output.txt"))
+	for i, line := range got {
+		if want := fmt.Sprintf("%03d", i); line != want {
+			t.Fatalf("line %d: got %q, want %q", i, line, want)
+		}
+	}
+}
+
+func TestExecuteExternalSortMultipleInputs(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t, "n", "")
+	cfg.TempDir = dir
+	cfg.BufferBytes = 3 * lineOverhead
+
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	writeLines(t, first, []string{"10", "3", "7"})
+	// Последняя строка без перевода строки не должна склеиться со следующим файлом.
+	if err := os.WriteFile(second, []byte("1\n8\n2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "output.txt")
+	if err := sortFiles([]string{first, second}, output, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(readLines(t, output), " "); got != "1 2 3 7 8 10" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMergeOnlyKeepsInputs(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t, "m", "", "1,1n")
+	cfg.TempDir = dir
+	cfg.MergeBatch = 2
+
+	var inputs []string
+	for shard := 0; shard < 5; shard++ {
+		path := filepath.Join(dir, fmt.Sprintf("shard%d.txt", shard))
+		var lines []string
+		for value := shard; value < 30; value += 5 {
+			lines = append(lines, fmt.Sprint(value))
+		}
+		writeLines(t, path, lines)
+		inputs = append(inputs, path)
+	}
+
+	output := filepath.Join(dir, "output.txt")
+	if err := sortFiles(inputs, output, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readLines(t, output)
+	if len(got) != 30 {
+		t.Fatalf("got %d lines, want 30", len(got))
+	}
+	for i, line := range got {
+		if line != fmt.Sprint(i) {
+			t.Fatalf("line %d: got %q", i, line)
+		}
+	}
+	for _, input := range inputs {
+		if _, err := os.Stat(input); err != nil {
+			t.Errorf("input %s was removed: %v", input, err)
+		}
+	}
+}
+
+func TestLastResortComparison(t *testing.T) {
+	lines := []string{"2 b", "1 z", "2 a", "1 c", "2 c"}
+
+	tests := []struct {
+		flags string
+		want  []string
+	}{
+		// Равные ключи упорядочиваются по всей строке.
+		{"", []string{"1 c", "1 z", "2 a", "2 b", "2 c"}},
+		// -r разворачивает и последнее средство.
+		{"r", []string{"2 c", "2 b", "2 a", "1 z", "1 c"}},
+		// -s сохраняет порядок ввода внутри групп, в том числе между блоками.
+		{"s", []string{"1 z", "1 c", "2 b", "2 a", "2 c"}},
+		{"sr", []string{"2 b", "2 a", "2 c", "1 z", "1 c"}},
+	}
+
+	for _, tt := range tests {
+		cfg := testConfig(t, tt.flags, "", "1,1")
+		for _, bufferSize := range []int{lineOverhead, 1 << 20} {
+			got := externalSort(t, cfg, lines, bufferSize)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("flags %q, buffer %d: got %q, want %q", tt.flags, bufferSize, got, tt.want)
+			}
+		}
+		if got := memorySort(cfg, lines); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("flags %q, in memory: got %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueKeepsFirstOfEachKeyGroup(t *testing.T) {
+	cfg := testConfig(t, "u", "", "1,1")
+	// Дубликаты в одном блоке идут перед строками с другими ключами: раньше
+	// слияние теряло хвост такого блока.
+	lines := []string{"b 1", "a 1", "a 2", "c 1", "b 2", "a 3", "d 1", "c 2"}
+	want := []string{"a 1", "b 1", "c 1", "d 1"}
+
+	for _, bufferSize := range []int{1, 3 * lineOverhead, 1 << 20} {
+		if got := externalSort(t, cfg, lines, bufferSize); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("buffer %d: got %q, want %q", bufferSize, got, want)
+		}
+	}
+	if got := memorySort(cfg, lines); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("in memory: got %q, want %q", got, want)
+	}
+}
+
+func TestCountPrefixesGroups(t *testing.T) {
+	cfg := testConfig(t, "", "", "1,1")
+	cfg.Count = true
+	lines := []string{"b 1", "a 1", "a 2", "c 1", "b 2", "a 3"}
+	want := []string{"      3 a 1", "      2 b 1", "      1 c 1"}
+
+	for _, unique := range []bool{false, true} {
+		cfg.UniqueOnly = unique
+		for _, bufferSize := range []int{1, 2 * lineOverhead, 1 << 20} {
+			if got := externalSort(t, cfg, lines, bufferSize); strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("unique=%v buffer %d: got %q, want %q", unique, bufferSize, got, want)
+			}
+		}
+	}
+}
@@ -1,21 +1,36 @@
 package sorter
 
 type QueueElement struct {
-	content     string
+	record      sortRecord
 	sourceIndex int
 }
 
-type MinimalHeap []QueueElement
+// MinimalHeap упорядочивает строки из блоков тем же компаратором,
+// которым сортировались сами блоки. Равные строки выходят в порядке номеров
+// прогонов, поэтому слияние сохраняет стабильность между блоками.
+type MinimalHeap struct {
+	elements []QueueElement
+	compare  func(first, second *sortRecord) int
+}
+
+func NewMinimalHeap(compare func(first, second *sortRecord) int) *MinimalHeap {
+	return &MinimalHeap{compare: compare}
+}
 
-func (h MinimalHeap) Len() int           { return len(h) }
-func (h MinimalHeap) Less(i, j int) bool { return h[i].content < h[j].content }
-func (h MinimalHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *MinimalHeap) Len() int { return len(h.elements) }
+func (h *MinimalHeap) Less(i, j int) bool {
+	if result := h.compare(&h.elements[i].record, &h.elements[j].record); result != 0 {
+		return result < 0
+	}
+	return h.elements[i].sourceIndex < h.elements[j].sourceIndex
+}
+func (h *MinimalHeap) Swap(i, j int) { h.elements[i], h.elements[j] = h.elements[j], h.elements[i] }
 
-func (h *MinimalHeap) Push(element any) { *h = append(*h, element.(QueueElement)) }
+func (h *MinimalHeap) Push(element any) { h.elements = append(h.elements, element.(QueueElement)) }
 func (h *MinimalHeap) Pop() any {
-	current := *h
+	current := h.elements
 	length := len(current)
 	element := current[length-1]
-	*h = current[:length-1]
+	h.elements = current[:length-1]
 	return element
 }
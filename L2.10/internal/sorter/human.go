@@ -0,0 +1,53 @@
+package sorter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// humanPowers — степени 1024 для суффиксов размеров. du -h и ls -lh
+// используют двоичные единицы, поэтому K, k и Ki означают одно и то же.
+var humanPowers = map[byte]int{
+	'K': 1, 'M': 2, 'G': 3, 'T': 4, 'P': 5, 'E': 6, 'Z': 7, 'Y': 8,
+}
+
+// parseHumanSize разбирает размер вида 512, 1.5K, 4,0K (десятичная запятая
+// в русской локали), 3Mi, 2GiB, 10kB или 7B и возвращает его в байтах.
+// Строка без числа считается нулём, как в GNU sort -h.
+func parseHumanSize(text string) float64 {
+	end := 0
+	if end < len(text) && (text[end] == '-' || text[end] == '+') {
+		end++
+	}
+	for end < len(text) && (isDigit(text[end]) || text[end] == '.' || text[end] == ',') {
+		end++
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(text[:end], ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+
+	suffix := text[end:]
+	if suffix == "" {
+		return value
+	}
+	power, ok := humanPowers[strings.ToUpper(suffix[:1])[0]]
+	if !ok {
+		return value // суффикс B или посторонний хвост: значение уже в байтах
+	}
+	return value * math.Pow(1024, float64(power))
+}
+
+func compareHumanSize(first, second string) int {
+	firstSize, secondSize := parseHumanSize(first), parseHumanSize(second)
+	switch {
+	case firstSize < secondSize:
+		return -1
+	case firstSize > secondSize:
+		return 1
+	default:
+		return 0
+	}
+}
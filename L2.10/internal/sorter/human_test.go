@@ -0,0 +1,90 @@
+package sorter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHumanSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"512", 512},
+		{"0", 0},
+		{"1.5K", 1536},
+		{"1.5k", 1536},
+		{"4,0K", 4096},
+		{"2Ki", 2048},
+		{"2KiB", 2048},
+		{"2KB", 2048},
+		{"7B", 7},
+		{"3M", 3 << 20},
+		{"1.1G", 1.1 * (1 << 30)},
+		{"2T", 2 << 40},
+		{"1P", 1 << 50},
+		{"1E", 1 << 60},
+		{"-1K", -1024},
+		{"", 0},
+		{"abc", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseHumanSize(tt.input); got != tt.want {
+			t.Errorf("parseHumanSize(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHumanSortDuOutput(t *testing.T) {
+	// Вывод `du -h --max-depth=1` в случайном порядке.
+	du := []string{
+		"1.1G\t./node_modules",
+		"4.0K\t./.github",
+		"36K\t./docs",
+		"1.5M\t./internal",
+		"12K\t./cmd",
+		"512\t./empty",
+		"980M\t./.git",
+		"2.1G\t.",
+	}
+
+	got := memorySort(testConfig(t, "h", ""), du)
+	want := []string{
+		"512\t./empty",
+		"4.0K\t./.github",
+		"12K\t./cmd",
+		"36K\t./docs",
+		"1.5M\t./internal",
+		"980M\t./.git",
+		"1.1G\t./node_modules",
+		"2.1G\t.",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHumanSortLsOutput(t *testing.T) {
+	// Колонка размеров `ls -lh`, включая байты без суффикса и 1.5K против 1500.
+	ls := []string{
+		"-rw-r--r-- 1 user user 1.5K Jan  2 10:00 notes.md",
+		"-rw-r--r-- 1 user user 1500 Jan  2 10:00 data.bin",
+		"-rw-r--r-- 1 user user  23M Jan  2 10:00 dump.sql",
+		"-rw-r--r-- 1 user user 4.0K Jan  2 10:00 main.go",
+		"-rw-r--r-- 1 user user 1.2G Jan  2 10:00 image.iso",
+		"-rw-r--r-- 1 user user  999 Jan  2 10:00 go.mod",
+	}
+
+	got := memorySort(testConfig(t, "", "", "5,5h"), ls)
+	var names []string
+	for _, line := range got {
+		fields := strings.Fields(line)
+		names = append(names, fields[len(fields)-1])
+	}
+
+	want := "go.mod data.bin notes.md main.go dump.sql image.iso"
+	if strings.Join(names, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(names, " "), want)
+	}
+}
@@ -0,0 +1,117 @@
+package sorter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// StdinName — имя входа, означающее стандартный ввод.
+const StdinName = "-"
+
+// Input — именованный источник записей. Open вызывается, только когда до
+// источника доходит очередь, поэтому при сортировке многих файлов открыт
+// лишь читаемый сейчас.
+type Input struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// FileInput возвращает источник, читающий файл path как есть.
+func FileInput(path string) Input {
+	return Input{
+		Name: path,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
+
+// ReaderInput оборачивает уже открытый поток; закрывает его вызывающий.
+func ReaderInput(name string, reader io.Reader) Input {
+	return Input{
+		Name: name,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(reader), nil
+		},
+	}
+}
+
+// SourceInput возвращает источник для входа из командной строки: "-" —
+// stdin, иначе файл. Сжатые gzip и bzip2 входы распознаются по сигнатуре
+// и распаковываются на лету.
+func SourceInput(name string, stdin io.Reader) Input {
+	raw := FileInput(name)
+	if name == StdinName {
+		raw = ReaderInput(name, stdin)
+	}
+	return Input{
+		Name: name,
+		Open: func() (io.ReadCloser, error) {
+			reader, err := raw.Open()
+			if err != nil {
+				return nil, err
+			}
+			decompressed, err := decompress(reader)
+			if err != nil {
+				reader.Close()
+				return nil, err
+			}
+			return &sourceReader{Reader: decompressed, closer: reader}, nil
+		},
+	}
+}
+
+// SourceInputs превращает список входов командной строки в источники.
+func SourceInputs(names []string, stdin io.Reader) []Input {
+	inputs := make([]Input, len(names))
+	for i, name := range names {
+		inputs[i] = SourceInput(name, stdin)
+	}
+	return inputs
+}
+
+// sourceReader читает распакованные данные и закрывает исходный поток.
+type sourceReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (sr *sourceReader) Close() error { return sr.closer.Close() }
+
+var (
+	gzipMagic      = []byte{0x1f, 0x8b, 0x08}
+	bzip2Magic     = []byte("BZh")
+	bzip2Block     = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59} // начало первого блока
+	bzip2EndStream = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90} // пустой поток
+)
+
+// decompress распознаёт сжатый вход по первым байтам. У bzip2 сигнатура
+// "BZh" проверяется вместе с уровнем и магией блока, чтобы не принять за
+// архив обычный текст, начинающийся с этих букв.
+func decompress(reader io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(reader)
+	header, err := buffered.Peek(10)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return gzip.NewReader(buffered)
+	case isBzip2Header(header):
+		return bzip2.NewReader(buffered), nil
+	default:
+		return buffered, nil
+	}
+}
+
+func isBzip2Header(header []byte) bool {
+	if len(header) < 10 || !bytes.HasPrefix(header, bzip2Magic) || header[3] < '1' || header[3] > '9' {
+		return false
+	}
+	return bytes.Equal(header[4:], bzip2Block) || bytes.Equal(header[4:], bzip2EndStream)
+}
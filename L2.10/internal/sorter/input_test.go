@@ -0,0 +1,91 @@
+package sorter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bzip2Lines — результат `printf 'c\na\nb\n' | bzip2`.
+var bzip2Lines = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x87, 0xb7, 0x65, 0xe4, 0x00, 0x00,
+	0x02, 0xc1, 0x00, 0x00, 0x10, 0x38, 0x00, 0x20, 0x00, 0x21, 0x9a, 0x68, 0x33, 0x4d, 0x1c, 0xb7,
+	0x8b, 0xb9, 0x22, 0x9c, 0x28, 0x48, 0x43, 0xdb, 0xb2, 0xf2, 0x00,
+}
+
+func gzipLines(t *testing.T, content string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return compressed.Bytes()
+}
+
+func TestSourceInputDecompresses(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"plain", []byte("c\na\nb\n"), "c\na\nb\n"},
+		{"gzip", gzipLines(t, "c\na\nb\n"), "c\na\nb\n"},
+		{"bzip2", bzip2Lines, "c\na\nb\n"},
+		{"text starting with BZh", []byte("BZh9 is not an archive\n"), "BZh9 is not an archive\n"},
+		{"short", []byte("x"), "x"},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := SourceInput(path, nil).Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortMixedSources(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t, "", "")
+	cfg.TempDir = dir
+
+	plain := filepath.Join(dir, "plain.txt")
+	writeLines(t, plain, []string{"e", "d"})
+	compressed := filepath.Join(dir, "old.log.gz")
+	if err := os.WriteFile(compressed, gzipLines(t, "f\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	inputs := SourceInputs([]string{plain, StdinName, compressed}, strings.NewReader("b\na\n"))
+	if err := ExecuteExternalSort(context.Background(), inputs, &output, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := output.String(), "a\nb\nc\nd\ne\nf\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
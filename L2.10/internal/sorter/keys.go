@@ -0,0 +1,95 @@
+package sorter
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// keyBounds возвращает байтовые границы ключа key в строке line.
+// Без разделителя поле состоит из ведущих пробелов и следующих за ними
+// непробельных символов, как в GNU sort; с разделителем (-t) поля могут быть
+// пустыми. Смещения символов считаются в рунах.
+func keyBounds(line string, key config.SortKey, separator string) (int, int) {
+	start := fieldStart(line, key.StartField, separator)
+	if key.IgnoreBlanks {
+		start = skipBlanks(line, start)
+	}
+	start = advanceChars(line, start, key.StartChar-1)
+
+	var end int
+	switch {
+	case key.EndField == 0:
+		end = len(line)
+	case key.EndChar == 0:
+		end = fieldEnd(line, key.EndField, separator)
+	default:
+		end = fieldStart(line, key.EndField, separator)
+		if key.IgnoreBlanks {
+			end = skipBlanks(line, end)
+		}
+		end = advanceChars(line, end, key.EndChar)
+	}
+
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// fieldStart возвращает смещение начала поля с номером field (с 1).
+func fieldStart(line string, field int, separator string) int {
+	pos := 0
+	for count := field - 1; count > 0 && pos < len(line); count-- {
+		if separator == "" {
+			pos = skipWord(line, pos)
+			continue
+		}
+		next := strings.Index(line[pos:], separator)
+		if next < 0 {
+			return len(line)
+		}
+		pos += next + len(separator)
+	}
+	return pos
+}
+
+// fieldEnd возвращает смещение конца поля с номером field, не включая разделитель.
+func fieldEnd(line string, field int, separator string) int {
+	pos := fieldStart(line, field, separator)
+	if separator == "" {
+		return skipWord(line, pos)
+	}
+	if next := strings.Index(line[pos:], separator); next >= 0 {
+		return pos + next
+	}
+	return len(line)
+}
+
+func skipWord(line string, pos int) int {
+	pos = skipBlanks(line, pos)
+	for pos < len(line) && !isBlank(line[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func skipBlanks(line string, pos int) int {
+	for pos < len(line) && isBlank(line[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func advanceChars(line string, pos, count int) int {
+	for ; count > 0 && pos < len(line); count-- {
+		_, size := utf8.DecodeRuneInString(line[pos:])
+		pos += size
+	}
+	return pos
+}
+
+func isBlank(b byte) bool {
+	return b == ' ' || b == '\t'
+}
@@ -0,0 +1,43 @@
+package sorter
+
+import (
+	"testing"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+func TestKeyBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		separator string
+		spec      string
+		want      string
+	}{
+		{"empty field keeps its position", "a::3", ":", "2,2", ""},
+		{"field after empty field", "a::3", ":", "3,3", "3"},
+		{"key to end of line", "a::3:4", ":", "3", "3:4"},
+		{"missing trailing field", "a:b", ":", "3,3", ""},
+		{"empty last field", "a:b:", ":", "3,3", ""},
+		{"chars past end of field", "a:b", ":", "2.2,2.3", ""},
+		{"multi-byte separator", "α→β→γ", "→", "2,2", "β"},
+		{"multi-byte chars in field", "α→βδε→γ", "→", "2.2,2.2", "δ"},
+		{"multi-byte separator to end", "α→β→γ", "→", "2", "β→γ"},
+		{"blank fields include leading blanks", "  a  b", "", "2,2", "  b"},
+		{"blank fields with -b", "  a  b", "", "2,2b", "b"},
+		{"missing blank field", "a b", "", "3,3", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := config.ParseKeySpec(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start, end := keyBounds(tt.line, key, tt.separator)
+			if got := tt.line[start:end]; got != tt.want {
+				t.Errorf("keyBounds(%q, -t%q -k%s) = %q, want %q", tt.line, tt.separator, tt.spec, got, tt.want)
+			}
+		})
+	}
+}
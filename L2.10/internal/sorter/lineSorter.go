@@ -1,12 +1,8 @@
 package sorter
 
 import (
-	"bufio"
 	"fmt"
-	"log"
-	"os"
 	"sort"
-	"strconv"
 	"strings"
 
 	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
@@ -38,91 +34,139 @@ func (ls *LineSorter) GetSortedLines() []string {
 	return ls.textLines
 }
 
+// PerformSort сортирует строки; с -u оставляет первую строку каждой группы
+// равных ключей. С --count дубликаты сохраняются: их подсчитывает слияние.
+// Ключи строк вычисляются один раз до сортировки (decorate–sort–undecorate),
+// а не при каждом сравнении.
 func (ls *LineSorter) PerformSort() {
-	sort.SliceStable(ls.textLines, func(i, j int) bool {
-		key1 := generateSortKey(ls.config, ls.textLines[i])
-		key2 := generateSortKey(ls.config, ls.textLines[j])
+	records := make([]sortRecord, len(ls.textLines))
+	for i, line := range ls.textLines {
+		records[i] = newSortRecord(ls.config, line)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return compareRecords(ls.config, &records[i], &records[j]) < 0
+	})
+	if ls.config.UniqueOnly && !ls.config.Count {
+		records = uniqueByKey(ls.config, records)
+	}
 
-		if key1 == "0" {
-			return true
-		}
-		if key2 == "0" {
-			return false
-		}
+	ls.textLines = ls.textLines[:len(records)]
+	for i := range records {
+		ls.textLines[i] = records[i].line
+	}
+}
 
-		if *ls.config.ReverseOrder {
-			return key1 >= key2
+// uniqueByKey убирает из отсортированных записей повторы ключа на месте,
+// оставляя первую запись каждой группы.
+func uniqueByKey(cfg config.SortConfig, records []sortRecord) []sortRecord {
+	if len(records) == 0 {
+		return records
+	}
+	unique := records[:1]
+	for i := 1; i < len(records); i++ {
+		if compareRecordKeys(cfg, &unique[len(unique)-1], &records[i]) != 0 {
+			unique = append(unique, records[i])
 		}
-		return key1 < key2
-	})
+	}
+	return unique
 }
 
-func generateSortKey(cfg config.SortConfig, inputLine string) string {
-	var targetPart string
-	lineParts := strings.Fields(inputLine)
+// sortRecord — строка вместе с её ключами сравнения. Ключи (особенно ключи
+// сопоставления --collate) дороги, поэтому строятся один раз на строку.
+type sortRecord struct {
+	line    string
+	keys    []string       // generateSortKey для каждого из cfg.Keys
+	general []generalValue // разобранные ключи -g по тем же индексам, nil без -g
+	whole   string         // ключ сопоставления всей строки для последнего средства (--collate)
+}
 
-	if *cfg.ColumnIndex < 1 || *cfg.ColumnIndex > len(lineParts) {
-		return "0"
-	} else {
-		targetPart = lineParts[*cfg.ColumnIndex-1]
+func newSortRecord(cfg config.SortConfig, line string) sortRecord {
+	record := sortRecord{line: line, keys: make([]string, len(cfg.Keys))}
+	for i, key := range cfg.Keys {
+		record.keys[i] = generateSortKey(cfg, key, line)
+		if key.General {
+			if record.general == nil {
+				record.general = make([]generalValue, len(cfg.Keys))
+			}
+			record.general[i] = parseGeneralNumeric(record.keys[i])
+		}
 	}
-
-	if *cfg.IgnoreSpaces {
-		targetPart = strings.TrimSpace(targetPart)
+	if cfg.Collate && !cfg.StableSort && !cfg.GroupsByKey() {
+		if isDefaultKey(cfg) {
+			record.whole = record.keys[0] // ключ и так вся строка
+		} else {
+			record.whole = collationKey(line)
+		}
 	}
+	return record
+}
 
-	if (*cfg.HumanReadable || *cfg.NumericSort) && *cfg.MonthSort {
-		panic("конфликт флагов: числовая сортировка и сортировка по месяцам несовместимы")
+// compareRecords сравнивает строки по ключам, а при их равенстве — целиком
+// ("последнее средство", как в GNU sort): по правилам сопоставления с
+// --collate, затем побайтово. С -s, -u или --count последнее средство
+// отключено, и равные по ключам строки сохраняют порядок ввода.
+func compareRecords(cfg config.SortConfig, first, second *sortRecord) int {
+	if result := compareRecordKeys(cfg, first, second); result != 0 || cfg.StableSort || cfg.GroupsByKey() {
+		return result
 	}
 
-	switch {
-	case *cfg.HumanReadable:
-		return parseHumanReadableSize(targetPart)
-	case *cfg.NumericSort:
-		return parseNumericValue(targetPart)
-	case *cfg.MonthSort:
-		return parseMonthValue(targetPart)
-	default:
-		return targetPart
+	result := strings.Compare(first.whole, second.whole)
+	if result == 0 {
+		result = strings.Compare(first.line, second.line)
+	}
+	if cfg.ReverseOrder {
+		result = -result
 	}
+	return result
 }
 
-func parseNumericValue(input string) string {
-	if value, err := strconv.ParseFloat(input, 64); err != nil {
-		return "0"
-	} else {
-		return fmt.Sprintf("%020.0f", value)
+// compareRecordKeys сравнивает записи только по ключам по порядку: следующий
+// ключ учитывается только при равенстве предыдущих.
+func compareRecordKeys(cfg config.SortConfig, first, second *sortRecord) int {
+	for i, key := range cfg.Keys {
+		var result int
+		if key.General {
+			result = compareGeneralValues(first.general[i], second.general[i])
+		} else {
+			result = compareKeyValues(key, first.keys[i], second.keys[i])
+		}
+		if key.Reverse {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
 	}
+	return 0
 }
 
-func parseHumanReadableSize(input string) string {
-	if len(input) == 0 {
-		return "0"
-	}
+func generateSortKey(cfg config.SortConfig, key config.SortKey, inputLine string) string {
+	start, end := keyBounds(inputLine, key, cfg.Separator)
+	targetPart := inputLine[start:end]
 
-	numericPart := input
-	factor := 1.0
-	suffix := strings.ToUpper(input[len(input)-1:])
-
-	if strings.ContainsAny(suffix, "KMGT") {
-		numericPart = strings.TrimRight(input, "KMGT")
-		switch suffix {
-		case "K":
-			factor = 1e3
-		case "M":
-			factor = 1e6
-		case "G":
-			factor = 1e9
-		case "T":
-			factor = 1e12
-		}
+	switch {
+	case key.Human:
+		return firstToken(targetPart)
+	case key.Numeric:
+		return numericPrefix(targetPart)
+	case key.General:
+		return firstToken(targetPart)
+	case key.Month:
+		return parseMonthValue(firstToken(targetPart))
+	case key.Random:
+		return randomKey(cfg.RandomSalt, textKey(key, targetPart))
+	default:
+		return textKey(key, targetPart)
 	}
+}
 
-	value, err := strconv.ParseFloat(numericPart, 64)
-	if err != nil {
-		return "0"
+// firstToken возвращает первое слово ключа: ключи вроде -k2 захватывают
+// хвост строки, но значение размера или месяца берётся только из начала.
+func firstToken(keyText string) string {
+	if tokens := strings.Fields(keyText); len(tokens) > 0 {
+		return tokens[0]
 	}
-	return fmt.Sprintf("%020.0f", value*factor)
+	return ""
 }
 
 func parseMonthValue(input string) string {
@@ -132,44 +176,3 @@ func parseMonthValue(input string) string {
 		return "0"
 	}
 }
-
-func isFileSorted(filePath string, cfg config.SortConfig) bool {
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.Printf("Ошибка открытия файла: %v", err)
-		return false
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	if !scanner.Scan() {
-		return true // Пустой файл считается отсортированным
-	}
-
-	previousLine := scanner.Text()
-	previousKey := generateSortKey(cfg, previousLine)
-
-	for scanner.Scan() {
-		currentLine := scanner.Text()
-		currentKey := generateSortKey(cfg, currentLine)
-
-		if *cfg.ReverseOrder {
-			if currentKey > previousKey {
-				return false
-			}
-		} else {
-			if currentKey < previousKey {
-				return false
-			}
-		}
-
-		previousKey = currentKey
-	}
-
-	if err := scanner.Err(); err != nil {
-		log.Printf("Ошибка чтения файла: %v", err)
-		return false
-	}
-
-	return true
-}
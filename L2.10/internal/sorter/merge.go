@@ -0,0 +1,150 @@
+package sorter
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// mergeLevel сливает прогоны группами по batchSize и возвращает промежуточные
+// прогоны следующего уровня. Слитые временные прогоны удаляются сразу, чтобы
+// на диске не лежали две копии данных; входные файлы -m не трогаем.
+func (es *ExternalSorter) mergeLevel(runs []Input) ([]Input, error) {
+	merged := make([]Input, 0, (len(runs)+es.batchSize-1)/es.batchSize)
+
+	for start := 0; start < len(runs); start += es.batchSize {
+		group := runs[start:min(start+es.batchSize, len(runs))]
+		runName := filepath.Join(es.workDir, fmt.Sprintf("merge_%d.tmp", es.mergedRuns))
+		es.mergedRuns++
+
+		if err := es.mergeToFile(group, runName); err != nil {
+			return nil, err
+		}
+		for _, run := range group {
+			if filepath.Dir(run.Name) == es.workDir {
+				os.Remove(run.Name)
+			}
+		}
+		merged = append(merged, es.runInput(runName))
+	}
+
+	return merged, nil
+}
+
+func (es *ExternalSorter) mergeToFile(runs []Input, target string) error {
+	runFile, err := es.createRun(target)
+	if err != nil {
+		return fmt.Errorf("не удалось создать промежуточный прогон: %w", err)
+	}
+	defer runFile.Close()
+
+	if err := es.mergeRuns(runs, runFile, false); err != nil {
+		return err
+	}
+	return runFile.Close()
+}
+
+// mergeRuns выполняет k-way слияние отсортированных прогонов через MinimalHeap.
+// Только финальное слияние (final) сворачивает группы равных ключей (-u,
+// --count) и сообщает о продвижении каждые progressInterval записей.
+func (es *ExternalSorter) mergeRuns(runs []Input, output io.Writer, final bool) error {
+	writer := NewConfigWriter(output, es.config)
+	grouping := final && es.config.GroupsByKey()
+	count := final && es.config.Count
+	var written int64
+	report := func() {
+		if final {
+			es.report(Progress{Stage: StageMerge, Records: written, Runs: len(runs)})
+		}
+	}
+
+	emit := func(line string, occurrences int64) error {
+		var prefix string
+		if count {
+			prefix = fmt.Sprintf("%7d ", occurrences)
+		}
+		if err := writer.Write(prefix + line); err != nil {
+			return fmt.Errorf("ошибка записи: %w", err)
+		}
+		written++
+		if written%progressInterval == 0 {
+			report()
+		}
+		return nil
+	}
+
+	blockScanners := make([]*RecordScanner, len(runs))
+	for i, run := range runs {
+		reader, err := run.Open()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		blockScanners[i] = NewConfigScanner(reader, es.config)
+	}
+
+	priorityQueue := NewMinimalHeap(es.compare)
+	heap.Init(priorityQueue)
+
+	for i, scanner := range blockScanners {
+		if scanner.Scan() {
+			heap.Push(priorityQueue, QueueElement{record: newSortRecord(es.config, scanner.Text()), sourceIndex: i})
+		} else if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Текущая группа равных ключей: её первая строка и размер. Равные ключи
+	// выходят из кучи подряд, первой — строка из более раннего прогона.
+	var group sortRecord
+	var groupSize, popped int64
+
+	for priorityQueue.Len() > 0 {
+		currentElement := heap.Pop(priorityQueue).(QueueElement)
+		popped++
+		if popped%cancelCheckInterval == 0 {
+			if err := es.ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case !grouping:
+			if err := emit(currentElement.record.line, 1); err != nil {
+				return err
+			}
+		case groupSize > 0 && compareRecordKeys(es.config, &group, &currentElement.record) == 0:
+			groupSize++
+		default:
+			if groupSize > 0 {
+				if err := emit(group.line, groupSize); err != nil {
+					return err
+				}
+			}
+			group, groupSize = currentElement.record, 1
+		}
+
+		scanner := blockScanners[currentElement.sourceIndex]
+		if scanner.Scan() {
+			heap.Push(priorityQueue, QueueElement{
+				record:      newSortRecord(es.config, scanner.Text()),
+				sourceIndex: currentElement.sourceIndex,
+			})
+		} else if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	if groupSize > 0 {
+		if err := emit(group.line, groupSize); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("ошибка записи: %w", err)
+	}
+	report()
+	return nil
+}
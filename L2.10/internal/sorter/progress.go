@@ -0,0 +1,21 @@
+package sorter
+
+// Этапы внешней сортировки, о которых сообщает Progress.
+const (
+	StageSort  = "sort"  // чтение входа и сортировка блоков
+	StageMerge = "merge" // слияние прогонов
+)
+
+// progressInterval — через сколько выведенных записей слияние сообщает о
+// продвижении. Отмена проверяется чаще, см. cancelCheckInterval.
+const progressInterval = 1 << 16
+
+// cancelCheckInterval — через сколько прочитанных записей проверяется отмена.
+const cancelCheckInterval = 1024
+
+// Progress описывает состояние сортировки в момент вызова обратного вызова.
+type Progress struct {
+	Stage   string // StageSort или StageMerge
+	Records int64  // на StageSort — прочитано записей, на StageMerge — выведено в результат
+	Runs    int    // на StageSort — записано блоков, на StageMerge — осталось слить прогонов
+}
@@ -0,0 +1,14 @@
+package sorter
+
+import "crypto/sha256"
+
+// randomKey заменяет ключ -R его хешем с солью запуска. Одинаковые ключи
+// получают одинаковый хеш и оказываются рядом, а порядок групп определяется
+// только солью, поэтому блоки и внешнее слияние видят один и тот же порядок.
+func randomKey(salt, text string) string {
+	hash := sha256.New()
+	hash.Write([]byte(salt))
+	hash.Write([]byte{0})
+	hash.Write([]byte(text))
+	return string(hash.Sum(nil))
+}
@@ -0,0 +1,88 @@
+package sorter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRandomSortGroupsEqualKeys(t *testing.T) {
+	var input []string
+	for i := 0; i < 50; i++ {
+		input = append(input, fmt.Sprintf("k%d %d", i%7, i))
+	}
+
+	cfg := testConfig(t, "s", "", "1,1R")
+	got := memorySort(cfg, input)
+
+	// Одинаковые ключи идут подряд, внутри группы -s сохраняет порядок ввода.
+	seen := make(map[string]bool)
+	previous := ""
+	for _, line := range got {
+		key := strings.Fields(line)[0]
+		if key != previous {
+			if seen[key] {
+				t.Fatalf("key %s appears in more than one group: %q", key, got)
+			}
+			seen[key] = true
+			previous = key
+		}
+	}
+	if len(seen) != 7 {
+		t.Fatalf("expected 7 groups, got %d", len(seen))
+	}
+}
+
+func TestRandomSortIsReproducible(t *testing.T) {
+	var input []string
+	for i := 0; i < 100; i++ {
+		input = append(input, fmt.Sprint(i))
+	}
+
+	sortWithSeed := func(seed string) string {
+		cfg := testConfig(t, "R", "")
+		cfg.RandomSalt = "seed:" + seed
+		return strings.Join(memorySort(cfg, input), " ")
+	}
+
+	first := sortWithSeed("1")
+	if first != sortWithSeed("1") {
+		t.Error("same seed produced different orders")
+	}
+	if first == sortWithSeed("2") {
+		t.Error("different seeds produced the same order")
+	}
+	if first == strings.Join(input, " ") {
+		t.Error("random sort left input unchanged")
+	}
+
+	// Внешняя сортировка с крошечным буфером даёт тот же порядок, что и в памяти.
+	cfg := testConfig(t, "R", "")
+	cfg.RandomSalt = "seed:1"
+	if got := strings.Join(externalSort(t, cfg, input, 3*lineOverhead), " "); got != first {
+		t.Errorf("external shuffle differs from in-memory shuffle")
+	}
+}
+
+func TestRandomSourceFile(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "random")
+	if err := os.WriteFile(source, []byte("some fixed random bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	salts := make([]string, 2)
+	for i := range salts {
+		cfg := testConfig(t, "R", "")
+		cfg.Seed = ""
+		cfg.RandomSource = source
+		if err := cfg.BuildRandomSalt(); err != nil {
+			t.Fatal(err)
+		}
+		salts[i] = cfg.RandomSalt
+	}
+	if salts[0] != salts[1] || salts[0] == "" {
+		t.Errorf("random source did not give a stable salt: %q", salts)
+	}
+}
@@ -0,0 +1,97 @@
+package sorter
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// RecordScanner читает записи, разделённые байтом-разделителем ('\n' или NUL
+// при -z). В отличие от bufio.Scanner длина записи не ограничена.
+// Последняя запись без завершающего разделителя тоже возвращается.
+type RecordScanner struct {
+	reader    *bufio.Reader
+	delimiter byte
+	record    string
+	err       error
+}
+
+func NewRecordScanner(input io.Reader, delimiter byte) *RecordScanner {
+	return &RecordScanner{reader: bufio.NewReader(input), delimiter: delimiter}
+}
+
+func (rs *RecordScanner) Scan() bool {
+	if rs.err != nil {
+		return false
+	}
+
+	record, err := rs.reader.ReadString(rs.delimiter)
+	if err != nil {
+		if err != io.EOF {
+			rs.err = err
+			return false
+		}
+		rs.err = io.EOF
+		if record == "" {
+			return false
+		}
+	}
+
+	rs.record = strings.TrimSuffix(record, string(rs.delimiter))
+	return true
+}
+
+func (rs *RecordScanner) Text() string { return rs.record }
+
+// Err возвращает первую ошибку чтения, кроме io.EOF.
+func (rs *RecordScanner) Err() error {
+	if rs.err == io.EOF {
+		return nil
+	}
+	return rs.err
+}
+
+// RecordWriter буферизует вывод и дописывает разделитель после каждой записи.
+type RecordWriter struct {
+	writer    *bufio.Writer
+	delimiter byte
+}
+
+func NewRecordWriter(output io.Writer, delimiter byte) *RecordWriter {
+	return &RecordWriter{writer: bufio.NewWriter(output), delimiter: delimiter}
+}
+
+func (rw *RecordWriter) Write(record string) error {
+	if _, err := rw.writer.WriteString(record); err != nil {
+		return err
+	}
+	return rw.writer.WriteByte(rw.delimiter)
+}
+
+// WriteString пишет text как есть, без разделителя записей.
+func (rw *RecordWriter) WriteString(text string) error {
+	_, err := rw.writer.WriteString(text)
+	return err
+}
+
+func (rw *RecordWriter) Flush() error { return rw.writer.Flush() }
+
+// recordDelimiter возвращает разделитель записей для конфигурации.
+func recordDelimiter(cfg config.SortConfig) byte {
+	if cfg.ZeroTerminated {
+		return 0
+	}
+	return '\n'
+}
+
+// NewConfigScanner и NewConfigWriter создают читатель и писатель записей
+// с разделителем из cfg (-z).
+func NewConfigScanner(input io.Reader, cfg config.SortConfig) *RecordScanner {
+	return NewRecordScanner(input, recordDelimiter(cfg))
+}
+
+func NewConfigWriter(output io.Writer, cfg config.SortConfig) *RecordWriter {
+	return NewRecordWriter(output, recordDelimiter(cfg))
+}
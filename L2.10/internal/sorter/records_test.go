@@ -0,0 +1,73 @@
+package sorter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordScanner(t *testing.T) {
+	long := strings.Repeat("x", 200*1024) // больше лимита bufio.Scanner в 64 KiB
+
+	tests := []struct {
+		name      string
+		input     string
+		delimiter byte
+		want      []string
+	}{
+		{"lines", "a\nb\n", '\n', []string{"a", "b"}},
+		{"no trailing newline", "a\nb", '\n', []string{"a", "b"}},
+		{"empty records", "\n\na\n", '\n', []string{"", "", "a"}},
+		{"nul", "a b\nc\x00d\x00", 0, []string{"a b\nc", "d"}},
+		{"long line", long + "\nshort\n", '\n', []string{long, "short"}},
+		{"empty input", "", '\n', nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewRecordScanner(strings.NewReader(tt.input), tt.delimiter)
+			var got []string
+			for scanner.Scan() {
+				got = append(got, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("got %d records %.40q, want %d records", len(got), got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestZeroTerminatedExternalSort(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t, "z", "")
+	cfg.TempDir = dir
+	cfg.BufferBytes = 2 * lineOverhead
+
+	// Имена файлов из find -print0 могут содержать переводы строк.
+	input := filepath.Join(dir, "files0")
+	records := []string{"./b\nfile", "./a file", "./c", strings.Repeat("z", 100*1024)}
+	if err := os.WriteFile(input, []byte(strings.Join(records, "\x00")+"\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	if err := ExecuteExternalSort(context.Background(), SourceInputs([]string{input}, nil), &output, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "./a file\x00./b\nfile\x00./c\x00" + records[3] + "\x00"
+	if output.String() != want {
+		t.Errorf("got %.60q, want %.60q", output.String(), want)
+	}
+
+	var diagnostics bytes.Buffer
+	if err := CheckSorted(context.Background(), bytes.NewReader(output.Bytes()), "-", cfg, &diagnostics); err != nil {
+		t.Errorf("sorted -z output failed check: %v %s", err, diagnostics.String())
+	}
+}
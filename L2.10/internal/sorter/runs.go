@@ -0,0 +1,81 @@
+package sorter
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// Временные прогоны (блоки и промежуточные слияния) с --compress-temp
+// пишутся сжатыми gzip. Сжатие идёт с наименьшим уровнем: прогоны читаются
+// один раз, и важнее скорость, чем последние проценты размера.
+
+// runWriter закрывает сжимающий писатель вместе с файлом.
+type runWriter struct {
+	io.Writer
+	compressor *gzip.Writer
+	file       *os.File
+}
+
+// createRun создаёт файл временного прогона path.
+func (es *ExternalSorter) createRun(path string) (*runWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !es.config.CompressTemp {
+		return &runWriter{Writer: file, file: file}, nil
+	}
+
+	compressor, err := gzip.NewWriterLevel(file, gzip.BestSpeed)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &runWriter{Writer: compressor, compressor: compressor, file: file}, nil
+}
+
+// Close дописывает сжатый поток и закрывает файл. Повторный вызов безопасен.
+func (rw *runWriter) Close() error {
+	if rw.compressor != nil {
+		if err := rw.compressor.Close(); err != nil {
+			rw.file.Close()
+			return err
+		}
+	}
+	return rw.file.Close()
+}
+
+// runReader закрывает распаковщик вместе с файлом.
+type runReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (rr *runReader) Close() error {
+	rr.Reader.Close()
+	return rr.file.Close()
+}
+
+// runInput возвращает источник для временного прогона path, распаковывая
+// его на лету, если прогоны сжимаются.
+func (es *ExternalSorter) runInput(path string) Input {
+	if !es.config.CompressTemp {
+		return FileInput(path)
+	}
+	return Input{
+		Name: path,
+		Open: func() (io.ReadCloser, error) {
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			decompressor, err := gzip.NewReader(file)
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			return &runReader{Reader: decompressor, file: file}, nil
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package sorter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressedRuns(t *testing.T) {
+	cfg := testConfig(t, "", ",", "2,2n", "1,1r")
+	cfg.CompressTemp = true
+	cfg.MergeBatch = 3
+
+	rng := rand.New(rand.NewSource(17))
+	lines := make([]string, 500)
+	for i := range lines {
+		lines[i] = randomLine(rng)
+	}
+
+	want := memorySort(cfg, lines)
+	got := externalSort(t, cfg, lines, 10*lineOverhead)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatal("sort with compressed runs differs from in-memory sort")
+	}
+}
+
+func TestCompressedRunsAreGzip(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(t, "", "")
+	cfg.TempDir = dir
+	cfg.CompressTemp = true
+
+	input := filepath.Join(dir, "input.txt")
+	writeLines(t, input, []string{"c", "b", "a", "d", "f", "e"})
+
+	es := NewExternalSorter(cfg, SourceInputs([]string{input}, nil), io.Discard, 2*lineOverhead)
+	if err := es.createWorkDir(); err != nil {
+		t.Fatal(err)
+	}
+	defer es.removeWorkDir()
+	if err := es.divideAndSortBlocks(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, run := range es.runs {
+		content, err := os.ReadFile(run.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+			t.Errorf("run %s is not gzip-compressed", run.Name)
+		}
+	}
+}
+
+// BenchmarkTempRuns сравнивает сортировку с обычными и сжатыми временными
+// прогонами; метрика temp-bytes — суммарный размер блоков на диске.
+func BenchmarkTempRuns(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"alpha", "beta", "gamma", "delta", "error", "warning", "info", "request", "served"}
+	lines := make([]string, 200000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("2024-01-%02d %s %s id=%d", rng.Intn(28)+1,
+			words[rng.Intn(len(words))], words[rng.Intn(len(words))], rng.Intn(1000000))
+	}
+
+	dir := b.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, compress := range []bool{false, true} {
+		b.Run(fmt.Sprintf("compress=%v", compress), func(b *testing.B) {
+			cfg := testConfig(b, "", "")
+			cfg.TempDir = dir
+			cfg.CompressTemp = compress
+
+			var tempBytes int64
+			for i := 0; i < b.N; i++ {
+				es := NewExternalSorter(cfg, SourceInputs([]string{input}, nil), io.Discard, 1<<20)
+				if err := es.createWorkDir(); err != nil {
+					b.Fatal(err)
+				}
+				if err := es.divideAndSortBlocks(); err != nil {
+					b.Fatal(err)
+				}
+				tempBytes = 0
+				for _, run := range es.runs {
+					if info, err := os.Stat(run.Name); err == nil {
+						tempBytes += info.Size()
+					}
+				}
+				if err := es.combineBlocks(); err != nil {
+					b.Fatal(err)
+				}
+				es.removeWorkDir()
+			}
+			b.ReportMetric(float64(tempBytes), "temp-bytes")
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package tempfiles
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Реестр временных каталогов и файлов текущего процесса. Они удаляются обычным
+// путём через Remove, а при прерывании (SIGINT) — разом через RemoveAll.
+var (
+	mu     sync.Mutex
+	active = make(map[string]struct{})
+)
+
+// Dir возвращает каталог для временных файлов: явно заданный (-T),
+// иначе $TMPDIR или системный каталог по умолчанию.
+func Dir(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	return os.TempDir()
+}
+
+// MkdirTemp создаёт уникальный для запуска каталог и регистрирует его.
+func MkdirTemp(dir, pattern string) (string, error) {
+	path, err := os.MkdirTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("не удалось создать временный каталог: %w", err)
+	}
+
+	mu.Lock()
+	active[path] = struct{}{}
+	mu.Unlock()
+	return path, nil
+}
+
+// CreateTemp создаёт временный файл и регистрирует его, как MkdirTemp.
+func CreateTemp(dir, pattern string) (*os.File, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать временный файл: %w", err)
+	}
+
+	mu.Lock()
+	active[file.Name()] = struct{}{}
+	mu.Unlock()
+	return file, nil
+}
+
+// CreateTempPerm — как CreateTemp, но файл создаётся с правами perm за
+// вычетом umask, а не 0600: os.CreateTemp не позволяет их задать.
+func CreateTempPerm(dir, pattern string, perm fs.FileMode) (*os.File, error) {
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if errors.Is(err, fs.ErrExist) && try < 10000 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать временный файл: %w", err)
+		}
+
+		mu.Lock()
+		active[file.Name()] = struct{}{}
+		mu.Unlock()
+		return file, nil
+	}
+}
+
+// Forget снимает путь с учёта, не удаляя его (например, после переименования).
+func Forget(path string) {
+	mu.Lock()
+	delete(active, path)
+	mu.Unlock()
+}
+
+// Remove удаляет файл или каталог вместе с содержимым и снимает его с учёта.
+func Remove(path string) error {
+	mu.Lock()
+	delete(active, path)
+	mu.Unlock()
+	return os.RemoveAll(path)
+}
+
+// RemoveAll удаляет все зарегистрированные каталоги.
+func RemoveAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	for path := range active {
+		os.RemoveAll(path)
+		delete(active, path)
+	}
+}
@@ -2,40 +2,166 @@ package config
 
 import (
 	"fmt"
-	"os"
-
-	flag "github.com/spf13/pflag"
+	"runtime"
+	"unicode/utf8"
 )
 
 type SortConfig struct {
-	ColumnIndex   *int
-	NumericSort   *bool
-	ReverseOrder  *bool
-	UniqueOnly    *bool
-	MonthSort     *bool
-	IgnoreSpaces  *bool
-	CheckSorted   *bool
-	HumanReadable *bool
+	KeySpecs       []string
+	NumericSort    bool
+	GeneralSort    bool
+	VersionSort    bool
+	RandomSort     bool
+	RandomSource   string
+	Seed           string
+	ReverseOrder   bool
+	UniqueOnly     bool
+	Count          bool
+	MonthSort      bool
+	IgnoreSpaces   bool
+	CheckSorted    bool
+	CheckQuiet     bool
+	MergeOnly      bool
+	StableSort     bool
+	HumanReadable  bool
+	FoldCase       bool
+	Dictionary     bool
+	PrintableOnly  bool
+	Collate        bool
+	FieldSep       string
+	BufferSize     string
+	Parallel       int
+	TempDir        string
+	OutputFile     string
+	Files0From     string
+	ZeroTerminated bool
+	CompressTemp   bool
+	BatchSize      int
+	Debug          bool
+
+	Keys        []SortKey // ключи сортировки, собранные из KeySpecs и глобальных флагов
+	Separator   string    // разделитель полей; пустая строка — поля разделяются пробелами
+	BufferBytes int       // бюджет памяти на блоки внешней сортировки в байтах
+	Workers     int       // число горутин, сортирующих блоки
+	MergeBatch  int       // сколько прогонов сливается за один проход
+	RandomSalt  string    // соль для хеширования ключей -R
+}
+
+// GroupsByKey сообщает, что строки с равными ключами сворачиваются в одну
+// (-u или --count). Тогда последнее средство сравнения отключено, и от
+// группы остаётся первая по порядку ввода строка.
+func (cfg *SortConfig) GroupsByKey() bool {
+	return cfg.UniqueOnly || cfg.Count
+}
+
+// Prepare вычисляет производные поля конфигурации из значений флагов.
+func (cfg *SortConfig) Prepare() error {
+	if err := cfg.BuildKeys(); err != nil {
+		return err
+	}
+	if err := cfg.BuildSeparator(); err != nil {
+		return err
+	}
+	if err := cfg.BuildRandomSalt(); err != nil {
+		return err
+	}
+	if err := cfg.BuildResources(); err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+// BuildResources определяет бюджет памяти (-S), число воркеров (--parallel)
+// и размер пакета слияния (--batch-size).
+func (cfg *SortConfig) BuildResources() error {
+	cfg.BufferBytes = DefaultBufferSize
+	if cfg.BufferSize != "" {
+		size, err := ParseBufferSize(cfg.BufferSize)
+		if err != nil {
+			return err
+		}
+		cfg.BufferBytes = size
+	}
+
+	cfg.Workers = min(runtime.NumCPU(), MaxDefaultWorkers)
+	if cfg.Parallel != 0 {
+		if cfg.Parallel < 0 {
+			return fmt.Errorf("число потоков должно быть положительным: %d", cfg.Parallel)
+		}
+		cfg.Workers = cfg.Parallel
+	}
+
+	cfg.MergeBatch = DefaultMergeBatch
+	if cfg.BatchSize != 0 {
+		if cfg.BatchSize < 2 {
+			return fmt.Errorf("размер пакета слияния должен быть не меньше 2: %d", cfg.BatchSize)
+		}
+		cfg.MergeBatch = cfg.BatchSize
+	}
+	return nil
 }
 
-func ParseCommandLine() (*SortConfig, []string) {
-	cfg := SortConfig{}
+// BuildKeys разбирает KeySpecs и переносит глобальные флаги на ключи без
+// собственных модификаторов. Без -k сортировка идёт по всей строке.
+func (cfg *SortConfig) BuildKeys() error {
+	global := SortKey{StartField: 1, StartChar: 1}
+	applyGlobalOptions(&global, cfg)
 
-	cfg.ColumnIndex = flag.IntP("column", "k", 1, "сортировка по указанному столбцу")
-	cfg.NumericSort = flag.BoolP("numeric", "n", false, "числовая сортировка")
-	cfg.ReverseOrder = flag.BoolP("reverse", "r", false, "обратный порядок")
-	cfg.UniqueOnly = flag.BoolP("unique", "u", false, "только уникальные строки")
-	cfg.MonthSort = flag.BoolP("month", "M", false, "сортировка по месяцам")
-	cfg.IgnoreSpaces = flag.BoolP("blanks", "b", false, "игнорировать пробелы")
-	cfg.CheckSorted = flag.BoolP("check", "c", false, "проверить сортировку")
-	cfg.HumanReadable = flag.BoolP("human", "h", false, "человекочитаемые размеры")
+	if len(cfg.KeySpecs) == 0 {
+		cfg.Keys = []SortKey{global}
+		return nil
+	}
 
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Использование: %s [опции] [файл]\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Опции:\n")
-		flag.PrintDefaults()
+	keys := make([]SortKey, 0, len(cfg.KeySpecs))
+	for _, spec := range cfg.KeySpecs {
+		key, err := ParseKeySpec(spec)
+		if err != nil {
+			return err
+		}
+		if !key.hasOptions() {
+			applyGlobalOptions(&key, cfg)
+		}
+		key.Collate = cfg.Collate
+		keys = append(keys, key)
 	}
+	cfg.Keys = keys
+	return nil
+}
+
+// BuildSeparator проверяет значение -t: разделитель должен быть ровно одним
+// символом (в том числе многобайтовым в UTF-8). Поддерживаются записи \t и \0.
+func (cfg *SortConfig) BuildSeparator() error {
+	cfg.Separator = ""
+	if cfg.FieldSep == "" {
+		return nil
+	}
+
+	separator := cfg.FieldSep
+	switch separator {
+	case "\\t":
+		separator = "\t"
+	case "\\0":
+		separator = "\x00"
+	}
+	if utf8.RuneCountInString(separator) != 1 || !utf8.ValidString(separator) {
+		return fmt.Errorf("разделитель полей должен быть одним символом: %q", cfg.FieldSep)
+	}
+
+	cfg.Separator = separator
+	return nil
+}
 
-	flag.Parse()
-	return &cfg, flag.Args()
+func applyGlobalOptions(key *SortKey, cfg *SortConfig) {
+	key.Numeric = cfg.NumericSort
+	key.General = cfg.GeneralSort
+	key.Version = cfg.VersionSort
+	key.Random = cfg.RandomSort
+	key.Reverse = cfg.ReverseOrder
+	key.Month = cfg.MonthSort
+	key.Human = cfg.HumanReadable
+	key.IgnoreBlanks = cfg.IgnoreSpaces
+	key.FoldCase = cfg.FoldCase
+	key.Dictionary = cfg.Dictionary
+	key.PrintableOnly = cfg.PrintableOnly
+	key.Collate = cfg.Collate
 }
@@ -0,0 +1,34 @@
+package config
+
+import "testing"
+
+func TestBuildSeparator(t *testing.T) {
+	tests := []struct {
+		fieldSep string
+		want     string
+		wantErr  bool
+	}{
+		{fieldSep: "", want: ""},
+		{fieldSep: ":", want: ":"},
+		{fieldSep: "→", want: "→"},
+		{fieldSep: "\\t", want: "\t"},
+		{fieldSep: "\\0", want: "\x00"},
+		{fieldSep: "::", wantErr: true},
+		{fieldSep: "ab", wantErr: true},
+		{fieldSep: "\xff", wantErr: true},
+		{fieldSep: "→→", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		cfg := SortConfig{FieldSep: tt.fieldSep}
+		err := cfg.BuildSeparator()
+		switch {
+		case tt.wantErr && err == nil:
+			t.Errorf("BuildSeparator(%q) = %q, want error", tt.fieldSep, cfg.Separator)
+		case !tt.wantErr && err != nil:
+			t.Errorf("BuildSeparator(%q): unexpected error %v", tt.fieldSep, err)
+		case !tt.wantErr && cfg.Separator != tt.want:
+			t.Errorf("BuildSeparator(%q) = %q, want %q", tt.fieldSep, cfg.Separator, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SortKey описывает один ключ сортировки в формате -k POS1[,POS2],
+// где POS имеет вид F[.C][модификаторы].
+type SortKey struct {
+	StartField int // номер поля, с которого начинается ключ (с 1)
+	StartChar  int // позиция символа в начальном поле (с 1)
+	EndField   int // номер поля, на котором ключ заканчивается; 0 — до конца строки
+	EndChar    int // позиция последнего символа в конечном поле; 0 — до конца поля
+
+	Numeric       bool // n
+	General       bool // g
+	Version       bool // V
+	Random        bool // R
+	Reverse       bool // r
+	Month         bool // M
+	Human         bool // h
+	IgnoreBlanks  bool // b
+	FoldCase      bool // f
+	Dictionary    bool // d
+	PrintableOnly bool // i
+
+	Collate bool // сопоставление UCA для русской локали (--collate), задаётся только глобально
+}
+
+func (k SortKey) hasOptions() bool {
+	return k.Numeric || k.General || k.Version || k.Random || k.Reverse || k.Month || k.Human || k.IgnoreBlanks ||
+		k.FoldCase || k.Dictionary || k.PrintableOnly
+}
+
+// ParseKeySpec разбирает спецификацию ключа, например "2,2n", "1,1r" или "3.2,3.5".
+func ParseKeySpec(spec string) (SortKey, error) {
+	var key SortKey
+
+	startSpec, endSpec, hasEnd := strings.Cut(spec, ",")
+
+	field, char, modifiers, err := parseKeyPosition(startSpec)
+	if err != nil {
+		return key, fmt.Errorf("неверная спецификация ключа %q: %v", spec, err)
+	}
+	if field < 1 {
+		return key, fmt.Errorf("неверная спецификация ключа %q: номер поля должен быть больше нуля", spec)
+	}
+	if char < 0 {
+		char = 1
+	} else if char == 0 {
+		return key, fmt.Errorf("неверная спецификация ключа %q: позиция символа должна быть больше нуля", spec)
+	}
+	key.StartField, key.StartChar = field, char
+	if err := applyModifiers(&key, modifiers); err != nil {
+		return key, fmt.Errorf("неверная спецификация ключа %q: %v", spec, err)
+	}
+
+	if !hasEnd {
+		return key, nil
+	}
+
+	field, char, modifiers, err = parseKeyPosition(endSpec)
+	if err != nil {
+		return key, fmt.Errorf("неверная спецификация ключа %q: %v", spec, err)
+	}
+	if field < 1 {
+		return key, fmt.Errorf("неверная спецификация ключа %q: номер поля должен быть больше нуля", spec)
+	}
+	if char < 0 {
+		char = 0
+	}
+	key.EndField, key.EndChar = field, char
+	if err := applyModifiers(&key, modifiers); err != nil {
+		return key, fmt.Errorf("неверная спецификация ключа %q: %v", spec, err)
+	}
+
+	return key, nil
+}
+
+// parseKeyPosition разбирает F[.C][модификаторы]. Если символ не указан, возвращает char = -1.
+func parseKeyPosition(position string) (field, char int, modifiers string, err error) {
+	digits := leadingDigits(position)
+	if digits == "" {
+		return 0, 0, "", fmt.Errorf("ожидался номер поля")
+	}
+	if field, err = strconv.Atoi(digits); err != nil {
+		return 0, 0, "", err
+	}
+	rest := position[len(digits):]
+
+	char = -1
+	if strings.HasPrefix(rest, ".") {
+		charDigits := leadingDigits(rest[1:])
+		if charDigits == "" {
+			return 0, 0, "", fmt.Errorf("ожидалась позиция символа после точки")
+		}
+		if char, err = strconv.Atoi(charDigits); err != nil {
+			return 0, 0, "", err
+		}
+		rest = rest[1+len(charDigits):]
+	}
+
+	return field, char, rest, nil
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+func applyModifiers(key *SortKey, modifiers string) error {
+	for _, modifier := range modifiers {
+		switch modifier {
+		case 'n':
+			key.Numeric = true
+		case 'g':
+			key.General = true
+		case 'V':
+			key.Version = true
+		case 'R':
+			key.Random = true
+		case 'r':
+			key.Reverse = true
+		case 'M':
+			key.Month = true
+		case 'h':
+			key.Human = true
+		case 'b':
+			key.IgnoreBlanks = true
+		case 'f':
+			key.FoldCase = true
+		case 'd':
+			key.Dictionary = true
+		case 'i':
+			key.PrintableOnly = true
+		default:
+			return fmt.Errorf("неизвестный модификатор %q", modifier)
+		}
+	}
+	return nil
+}
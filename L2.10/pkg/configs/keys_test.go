@@ -0,0 +1,69 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeySpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    SortKey
+		wantErr bool
+	}{
+		{spec: "2", want: SortKey{StartField: 2, StartChar: 1}},
+		{spec: "2,2n", want: SortKey{StartField: 2, StartChar: 1, EndField: 2, Numeric: true}},
+		{spec: "3.2,3.5", want: SortKey{StartField: 3, StartChar: 2, EndField: 3, EndChar: 5}},
+		{spec: "2,2.0", want: SortKey{StartField: 2, StartChar: 1, EndField: 2}},
+		{spec: "1br,1", want: SortKey{StartField: 1, StartChar: 1, EndField: 1, IgnoreBlanks: true, Reverse: true}},
+		{spec: "1,2fM", want: SortKey{StartField: 1, StartChar: 1, EndField: 2, FoldCase: true, Month: true}},
+		{spec: "1.0", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "1,0", wantErr: true},
+		{spec: "2z", wantErr: true},
+		{spec: "", wantErr: true},
+		{spec: "n", wantErr: true},
+		{spec: ",2", wantErr: true},
+		{spec: "1,", wantErr: true},
+		{spec: "1.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKeySpec(tt.spec)
+		switch {
+		case tt.wantErr && err == nil:
+			t.Errorf("ParseKeySpec(%q) = %+v, want error", tt.spec, got)
+		case !tt.wantErr && err != nil:
+			t.Errorf("ParseKeySpec(%q): unexpected error %v", tt.spec, err)
+		case !tt.wantErr && got != tt.want:
+			t.Errorf("ParseKeySpec(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestBuildKeysGlobalOptions(t *testing.T) {
+	cfg := SortConfig{KeySpecs: []string{"1,1", "2,2n", "3,3b"}, ReverseOrder: true, FoldCase: true}
+	if err := cfg.BuildKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SortKey{
+		// Ключ без модификаторов наследует глобальные флаги.
+		{StartField: 1, StartChar: 1, EndField: 1, Reverse: true, FoldCase: true},
+		// Ключ с собственными модификаторами не наследует ни одного.
+		{StartField: 2, StartChar: 1, EndField: 2, Numeric: true},
+		{StartField: 3, StartChar: 1, EndField: 3, IgnoreBlanks: true},
+	}
+	if !reflect.DeepEqual(cfg.Keys, want) {
+		t.Errorf("got %+v\nwant %+v", cfg.Keys, want)
+	}
+
+	// Без -k глобальные флаги применяются ко всей строке.
+	cfg = SortConfig{NumericSort: true}
+	if err := cfg.BuildKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []SortKey{{StartField: 1, StartChar: 1, Numeric: true}}; !reflect.DeepEqual(cfg.Keys, want) {
+		t.Errorf("got %+v, want %+v", cfg.Keys, want)
+	}
+}
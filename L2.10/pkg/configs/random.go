@@ -0,0 +1,71 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+)
+
+// randomSaltSize — сколько байт случайности берётся из --random-source или crypto/rand.
+const randomSaltSize = 32
+
+// BuildRandomSalt определяет соль для ключей -R. Порядок перемешивания зависит
+// только от соли и значения ключа, поэтому одинаковые --seed или
+// --random-source дают воспроизводимый результат.
+func (cfg *SortConfig) BuildRandomSalt() error {
+	cfg.RandomSalt = ""
+	if !cfg.hasRandomKey() {
+		return nil
+	}
+
+	seed := cfg.Seed != ""
+	source := cfg.RandomSource != ""
+
+	switch {
+	case seed && source:
+		return fmt.Errorf("--seed и --random-source нельзя указывать одновременно")
+	case seed:
+		cfg.RandomSalt = "seed:" + cfg.Seed
+	case source:
+		salt, err := readRandomSource(cfg.RandomSource)
+		if err != nil {
+			return err
+		}
+		cfg.RandomSalt = salt
+	default:
+		salt := make([]byte, randomSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("не удалось получить случайные данные: %w", err)
+		}
+		cfg.RandomSalt = string(salt)
+	}
+	return nil
+}
+
+func (cfg *SortConfig) hasRandomKey() bool {
+	for _, key := range cfg.Keys {
+		if key.Random {
+			return true
+		}
+	}
+	return false
+}
+
+func readRandomSource(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("не удалось открыть источник случайности: %w", err)
+	}
+	defer file.Close()
+
+	salt := make([]byte, randomSaltSize)
+	n, err := io.ReadFull(file, salt)
+	if n == 0 {
+		return "", fmt.Errorf("источник случайности %s пуст", path)
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("ошибка чтения источника случайности: %w", err)
+	}
+	return string(salt[:n]), nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultBufferSize = 64 << 20 // бюджет памяти по умолчанию
+	MaxDefaultWorkers = 8        // верхняя граница числа воркеров по умолчанию, как в GNU sort
+	DefaultMergeBatch = 16       // число одновременно сливаемых прогонов, как NMERGE в GNU sort
+)
+
+var bufferSuffixes = map[byte]int{
+	'b': 0, 'B': 0,
+	'k': 1, 'K': 1,
+	'm': 2, 'M': 2,
+	'g': 3, 'G': 3,
+	't': 4, 'T': 4,
+}
+
+// ParseBufferSize разбирает размер буфера в формате -S: число с необязательным
+// суффиксом b, K, M, G или T (степени 1024). Число без суффикса — килобайты, как в GNU sort.
+func ParseBufferSize(value string) (int, error) {
+	original := value
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("пустой размер буфера")
+	}
+
+	power := 1
+	if exponent, ok := bufferSuffixes[value[len(value)-1]]; ok {
+		power = exponent
+		value = value[:len(value)-1]
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || number == 0 {
+		return 0, fmt.Errorf("неверный размер буфера %q", original)
+	}
+
+	size := float64(number) * math.Pow(1024, float64(power))
+	if size > math.MaxInt {
+		return 0, fmt.Errorf("слишком большой размер буфера %q", original)
+	}
+	return int(size), nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import "testing"
+
+func TestParseBufferSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"512M", 512 << 20, false},
+		{"1G", 1 << 30, false},
+		{"100b", 100, false},
+		{"64", 64 << 10, false}, // без суффикса — килобайты
+		{"2k", 2 << 10, false},
+
+		{"", 0, true},
+		{"0", 0, true},
+		{"-5M", 0, true},
+		{"1.5G", 0, true},
+		{"12X", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBufferSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBufferSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseBufferSize(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
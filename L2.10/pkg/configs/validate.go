@@ -0,0 +1,68 @@
+package config
+
+import "fmt"
+
+// Validate проверяет сочетания опций один раз до начала сортировки и
+// сообщает о конфликтах так же, как GNU sort: "options '-hn' are incompatible".
+// Ключи проверяются уже с унаследованными глобальными флагами, поэтому
+// "-n -M" и "-k1,1nM" дают одну и ту же ошибку.
+func (cfg *SortConfig) Validate() error {
+	if cfg.CheckSorted && cfg.CheckQuiet {
+		return incompatible("cC")
+	}
+	if cfg.CheckSorted && cfg.OutputFile != "" {
+		return incompatible("co")
+	}
+	if cfg.CheckQuiet && cfg.OutputFile != "" {
+		return incompatible("Co")
+	}
+
+	for _, key := range cfg.Keys {
+		if key.hasOrderingConflict() {
+			return incompatible(key.orderingOptions())
+		}
+	}
+	return nil
+}
+
+func incompatible(options string) error {
+	return fmt.Errorf("options '-%s' are incompatible", options)
+}
+
+// hasOrderingConflict сообщает, задаёт ли ключ больше одного способа
+// сравнения: числовые режимы, месяцы, версии, -R и фильтры -d/-i
+// взаимоисключающие, как в GNU sort.
+func (k SortKey) hasOrderingConflict() bool {
+	count := 0
+	for _, set := range []bool{k.Numeric, k.General, k.Human, k.Month, k.Version || k.Random || k.Dictionary || k.PrintableOnly} {
+		if set {
+			count++
+		}
+	}
+	return count > 1
+}
+
+// orderingOptions перечисляет модификаторы ключа в порядке GNU sort,
+// опуская -b и -r: они ни с чем не конфликтуют.
+func (k SortKey) orderingOptions() string {
+	var options []byte
+	for _, option := range []struct {
+		set    bool
+		letter byte
+	}{
+		{k.Dictionary, 'd'},
+		{k.FoldCase, 'f'},
+		{k.General, 'g'},
+		{k.Human, 'h'},
+		{k.PrintableOnly, 'i'},
+		{k.Month, 'M'},
+		{k.Numeric, 'n'},
+		{k.Random, 'R'},
+		{k.Version, 'V'},
+	} {
+		if option.set {
+			options = append(options, option.letter)
+		}
+	}
+	return string(options)
+}
@@ -0,0 +1,46 @@
+package config
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SortConfig
+		wantErr string
+	}{
+		{"numeric only", SortConfig{NumericSort: true}, ""},
+		{"reverse and blanks do not conflict", SortConfig{NumericSort: true, ReverseOrder: true, IgnoreSpaces: true}, ""},
+		{"fold case with month", SortConfig{MonthSort: true, FoldCase: true}, ""},
+		{"human and numeric", SortConfig{HumanReadable: true, NumericSort: true}, "options '-hn' are incompatible"},
+		{"numeric and month", SortConfig{NumericSort: true, MonthSort: true}, "options '-Mn' are incompatible"},
+		{"dictionary and general", SortConfig{Dictionary: true, GeneralSort: true}, "options '-dg' are incompatible"},
+		{"version and random", SortConfig{VersionSort: true, RandomSort: true, NumericSort: true}, "options '-nRV' are incompatible"},
+		{"per-key conflict", SortConfig{KeySpecs: []string{"1,1", "2,2Mnr"}}, "options '-Mn' are incompatible"},
+		{"inherited by key", SortConfig{KeySpecs: []string{"2"}, HumanReadable: true, GeneralSort: true}, "options '-gh' are incompatible"},
+		{"key options replace globals", SortConfig{KeySpecs: []string{"2n"}, MonthSort: true}, ""},
+		{"check with output", SortConfig{CheckSorted: true, OutputFile: "out"}, "options '-co' are incompatible"},
+		{"quiet check with output", SortConfig{CheckQuiet: true, OutputFile: "out"}, "options '-Co' are incompatible"},
+		{"both checks", SortConfig{CheckSorted: true, CheckQuiet: true}, "options '-cC' are incompatible"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Prepare()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepareRejectsInvalidKeys(t *testing.T) {
+	for _, spec := range []string{"0", "1.0", "x", "1,0", "1z", "1,"} {
+		cfg := SortConfig{KeySpecs: []string{spec}}
+		if err := cfg.Prepare(); err == nil {
+			t.Errorf("key %q: expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package extsort — внешняя сортировка строк для встраивания в другие
+// программы: данные читаются из io.Reader, результат пишется в io.Writer,
+// а объём памяти ограничен независимо от размера входа. Утилита sort
+// использует этот же API.
+package extsort
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GkadyrG/L2/L2.10/internal/sorter"
+	config "github.com/GkadyrG/L2/L2.10/pkg/configs"
+)
+
+// Options — настройки сортировки. Нулевое значение сортирует строки
+// побайтово с параметрами памяти по умолчанию. Названия полей следуют
+// длинным флагам утилиты sort.
+type Options struct {
+	// Keys — ключи сортировки в формате -k POS1[,POS2][модификаторы],
+	// например "2,2n". Без ключей сравниваются строки целиком.
+	Keys []string
+
+	// Способы сравнения и модификаторы (-n, -g, -h, -M, -V, -R, -r, -b, -f,
+	// -d, -i). Они применяются ко всей строке или к ключам без собственных
+	// модификаторов.
+	Numeric           bool
+	GeneralNumeric    bool
+	HumanNumeric      bool
+	Month             bool
+	Version           bool
+	Random            bool
+	Reverse           bool
+	IgnoreBlanks      bool
+	FoldCase          bool
+	Dictionary        bool
+	IgnoreNonprinting bool
+
+	Collate   bool // сопоставление UCA для русской локали
+	Unique    bool // оставить первую строку каждой группы равных ключей (-u)
+	Count     bool // выводить группы равных ключей один раз с числом повторений (--count)
+	Stable    bool // не сравнивать строки целиком при равных ключах (-s)
+	MergeOnly bool // входы уже отсортированы, только слить (-m)
+
+	// FieldSeparator — символ-разделитель полей; пусто — поля отделяются
+	// переходом от пробелов к непробельным символам.
+	FieldSeparator string
+	ZeroTerminated bool // записи разделяются нулевым байтом, а не '\n'
+
+	// RandomSalt задаёт порядок перемешивания для Random: одинаковая соль
+	// даёт одинаковый порядок. Пусто — новая случайная соль при каждом вызове.
+	RandomSalt []byte
+
+	BufferSize   int    // бюджет памяти на блоки в байтах; 0 — по умолчанию
+	Parallel     int    // число горутин, сортирующих блоки; 0 — по числу CPU, но не больше 8
+	MergeBatch   int    // сколько прогонов сливать за один проход; 0 — по умолчанию
+	TempDir      string // каталог для временных файлов; пусто — $TMPDIR
+	CompressTemp bool   // сжимать временные файлы gzip
+
+	// Progress, если задан, вызывается по мере чтения блоков и слияния
+	// прогонов из горутины, вызвавшей Sort.
+	Progress func(Progress)
+}
+
+// Progress описывает состояние сортировки; см. StageSort и StageMerge.
+type Progress = sorter.Progress
+
+// Этапы сортировки в Progress.Stage.
+const (
+	StageSort  = sorter.StageSort
+	StageMerge = sorter.StageMerge
+)
+
+// ErrDisorder возвращает Check, если вход не отсортирован.
+var ErrDisorder = sorter.ErrDisorder
+
+// Sort сортирует строки из input и пишет их в output. С MergeOnly input
+// считается уже отсортированным и копируется без пересортировки.
+// Отмена ctx прерывает сортировку и возвращает ctx.Err(); временные файлы
+// удаляются в любом случае.
+func Sort(ctx context.Context, input io.Reader, output io.Writer, opts Options) error {
+	return sortInputs(ctx, []sorter.Input{sorter.ReaderInput("-", input)}, output, opts)
+}
+
+// SortFiles сортирует содержимое файлов paths как один поток строк, а с
+// MergeOnly сливает уже отсортированные файлы. Путь "-" означает os.Stdin;
+// файлы, сжатые gzip или bzip2, распаковываются на лету.
+func SortFiles(ctx context.Context, paths []string, output io.Writer, opts Options) error {
+	return sortInputs(ctx, sorter.SourceInputs(paths, os.Stdin), output, opts)
+}
+
+// Check проверяет, что input отсортирован по правилам opts, и возвращает
+// ErrDisorder при первом нарушении. Если diagnostics не nil, нарушение
+// описывается в нём в формате GNU sort с именем входа name.
+func Check(ctx context.Context, input io.Reader, name string, opts Options, diagnostics io.Writer) error {
+	cfg, err := opts.sortConfig()
+	if err != nil {
+		return err
+	}
+	return sorter.CheckSorted(ctx, input, name, cfg, diagnostics)
+}
+
+// CheckFile проверяет файл path так же, как Check; "-" означает os.Stdin,
+// сжатые файлы распаковываются.
+func CheckFile(ctx context.Context, path string, opts Options, diagnostics io.Writer) error {
+	input, err := sorter.SourceInput(path, os.Stdin).Open()
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	return Check(ctx, input, path, opts, diagnostics)
+}
+
+func sortInputs(ctx context.Context, inputs []sorter.Input, output io.Writer, opts Options) error {
+	cfg, err := opts.sortConfig()
+	if err != nil {
+		return err
+	}
+	return sorter.ExecuteExternalSort(ctx, inputs, output, cfg, opts.Progress)
+}
+
+// sortConfig переводит opts во внутренние настройки сортировщика и
+// вычисляет производные поля: ключи, разделитель, ресурсы и соль -R.
+func (opts Options) sortConfig() (config.SortConfig, error) {
+	if opts.BufferSize < 0 {
+		return config.SortConfig{}, fmt.Errorf("размер буфера должен быть положительным: %d", opts.BufferSize)
+	}
+
+	cfg := config.SortConfig{
+		KeySpecs:       opts.Keys,
+		NumericSort:    opts.Numeric,
+		GeneralSort:    opts.GeneralNumeric,
+		HumanReadable:  opts.HumanNumeric,
+		MonthSort:      opts.Month,
+		VersionSort:    opts.Version,
+		RandomSort:     opts.Random,
+		ReverseOrder:   opts.Reverse,
+		IgnoreSpaces:   opts.IgnoreBlanks,
+		FoldCase:       opts.FoldCase,
+		Dictionary:     opts.Dictionary,
+		PrintableOnly:  opts.IgnoreNonprinting,
+		Collate:        opts.Collate,
+		UniqueOnly:     opts.Unique,
+		Count:          opts.Count,
+		StableSort:     opts.Stable,
+		MergeOnly:      opts.MergeOnly,
+		FieldSep:       opts.FieldSeparator,
+		ZeroTerminated: opts.ZeroTerminated,
+		Parallel:       opts.Parallel,
+		BatchSize:      opts.MergeBatch,
+		TempDir:        opts.TempDir,
+		CompressTemp:   opts.CompressTemp,
+	}
+	if err := cfg.BuildKeys(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.BuildSeparator(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.BuildResources(); err != nil {
+		return cfg, err
+	}
+	if opts.BufferSize > 0 {
+		cfg.BufferBytes = opts.BufferSize
+	}
+	cfg.RandomSalt = string(opts.RandomSalt)
+	if cfg.RandomSalt == "" {
+		if err := cfg.BuildRandomSalt(); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, cfg.Validate()
+}
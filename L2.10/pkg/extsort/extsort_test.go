@@ -0,0 +1,126 @@
+package extsort
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSortReader(t *testing.T) {
+	opts := Options{Keys: []string{"2,2n"}, TempDir: t.TempDir()}
+
+	var output bytes.Buffer
+	if err := Sort(context.Background(), strings.NewReader("b 10\na 9\nc 1\n"), &output, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := output.String(), "c 1\na 9\nb 10\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSortInvalidOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{Keys: []string{"0"}},
+		{Numeric: true, Month: true},
+		{FieldSeparator: "ab"},
+		{BufferSize: -1},
+		{MergeBatch: 1},
+	} {
+		if err := Sort(context.Background(), strings.NewReader("a\n"), &bytes.Buffer{}, opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
+
+func TestSortProgress(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "%04d\n", (i*7919)%1000)
+	}
+
+	var reports []Progress
+	opts := Options{
+		BufferSize: 4 << 10,
+		Parallel:   1,
+		MergeBatch: 2,
+		TempDir:    t.TempDir(),
+		Progress:   func(progress Progress) { reports = append(reports, progress) },
+	}
+	var output bytes.Buffer
+	if err := Sort(context.Background(), strings.NewReader(input.String()), &output, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) == 0 || reports[0].Stage != StageSort {
+		t.Fatalf("expected sort stage first, got %+v", reports)
+	}
+	last := reports[len(reports)-1]
+	if last.Stage != StageMerge || last.Records != 1000 {
+		t.Errorf("last report = %+v, want merge of 1000 records", last)
+	}
+}
+
+func TestSortCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	input := strings.Repeat("line\n", 10000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := Options{
+		BufferSize: 4 << 10,
+		TempDir:    tempDir,
+		Progress: func(progress Progress) {
+			if progress.Stage == StageSort {
+				cancel()
+			}
+		},
+	}
+
+	err := Sort(ctx, strings.NewReader(input), &bytes.Buffer{}, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var opts Options
+
+	var diagnostics bytes.Buffer
+	err := Check(context.Background(), strings.NewReader("a\nc\nb\n"), "data", opts, &diagnostics)
+	if !errors.Is(err, ErrDisorder) {
+		t.Fatalf("got %v, want ErrDisorder", err)
+	}
+	if got, want := diagnostics.String(), "sort: data:3: disorder: b\n"; got != want {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+}
+
+func TestSortRandomSalt(t *testing.T) {
+	input := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	shuffle := func(salt string) string {
+		var output bytes.Buffer
+		opts := Options{Random: true, RandomSalt: []byte(salt)}
+		if err := Sort(context.Background(), strings.NewReader(input), &output, opts); err != nil {
+			t.Fatal(err)
+		}
+		return output.String()
+	}
+
+	if first, second := shuffle("salt"), shuffle("salt"); first != second {
+		t.Errorf("same salt gave different orders:\n%s\n%s", first, second)
+	}
+	if shuffle("salt") == shuffle("other salt") && shuffle("salt") == shuffle("third") {
+		t.Error("order does not depend on the salt")
+	}
+}
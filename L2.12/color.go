@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// colorMode is the value of --color. A bare --color means auto, as in GNU grep.
+type colorMode string
+
+func (c *colorMode) String() string { return string(*c) }
+
+func (c *colorMode) Set(value string) error {
+	switch value {
+	case "true", "auto", "tty", "if-tty":
+		*c = "auto"
+	case "always", "yes", "force":
+		*c = "always"
+	case "never", "no", "none":
+		*c = "never"
+	default:
+		return fmt.Errorf("invalid color mode %q: want auto, always or never", value)
+	}
+	return nil
+}
+
+// IsBoolFlag lets --color be given without a value.
+func (c *colorMode) IsBoolFlag() bool { return true }
+
+// enabled reports whether output to out should be highlighted: always, or
+// with auto only when out is a terminal that understands escape sequences.
+func (c colorMode) enabled(out *os.File) bool {
+	switch c {
+	case "always":
+		return true
+	case "auto":
+		info, err := out.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+	default:
+		return false
+	}
+}
+
+// colorRole names a part of the output that can be colored.
+type colorRole int
+
+const (
+	colorSelectedMatch colorRole = iota // ms: matches in selected lines
+	colorContextMatch                   // mc: matches in context lines
+	colorSelectedLine                   // sl: the rest of selected lines
+	colorContextLine                    // cx: the rest of context lines
+	colorFileName                       // fn
+	colorLineNumber                     // ln
+	colorByteOffset                     // bn
+	colorSeparator                      // se: ':' and '-' after prefixes, group separators
+	colorRoles
+)
+
+// grepColorNames maps GREP_COLORS keys to roles.
+var grepColorNames = map[string]colorRole{
+	"ms": colorSelectedMatch, "mc": colorContextMatch, "sl": colorSelectedLine, "cx": colorContextLine,
+	"fn": colorFileName, "ln": colorLineNumber, "bn": colorByteOffset, "se": colorSeparator,
+}
+
+// palette holds the SGR parameters for each role. An empty value leaves
+// that part uncolored; a nil palette disables colors altogether.
+type palette struct {
+	sgr     [colorRoles]string
+	reverse bool // rv: swap sl and cx with -v
+	noErase bool // ne: do not clear to end of line after each sequence
+}
+
+// parseGrepColors starts from the GNU grep defaults and applies spec, a
+// GREP_COLORS value such as "ms=01;32:fn=34:ne". Unknown entries are ignored.
+func parseGrepColors(spec string) *palette {
+	p := &palette{}
+	p.sgr[colorSelectedMatch] = "01;31"
+	p.sgr[colorContextMatch] = "01;31"
+	p.sgr[colorFileName] = "35"
+	p.sgr[colorLineNumber] = "32"
+	p.sgr[colorByteOffset] = "32"
+	p.sgr[colorSeparator] = "36"
+
+	for _, entry := range strings.Split(spec, ":") {
+		name, value, hasValue := strings.Cut(entry, "=")
+		role, known := grepColorNames[name]
+		switch {
+		case name == "mt" && hasValue:
+			p.sgr[colorSelectedMatch], p.sgr[colorContextMatch] = value, value
+		case name == "rv" && !hasValue:
+			p.reverse = true
+		case name == "ne" && !hasValue:
+			p.noErase = true
+		case known && hasValue:
+			p.sgr[role] = value
+		}
+	}
+	return p
+}
+
+// lineRoles returns the roles for a line and the matches within it:
+// selected lines use sl and ms, context lines cx and mc.
+func (p *palette) lineRoles(selected, invert bool) (line, match colorRole) {
+	line, match = colorContextLine, colorContextMatch
+	if selected {
+		line, match = colorSelectedLine, colorSelectedMatch
+	}
+	if p != nil && p.reverse && invert {
+		line = colorSelectedLine + colorContextLine - line
+	}
+	return line, match
+}
+
+// paint writes text wrapped in the SGR sequence for role. With a nil
+// palette, an uncolored role or empty text, text is written unchanged.
+func (p *palette) paint(w io.Writer, role colorRole, text string) {
+	if p == nil || p.sgr[role] == "" || text == "" {
+		io.WriteString(w, text)
+		return
+	}
+	erase := "\x1b[K"
+	if p.noErase {
+		erase = ""
+	}
+	io.WriteString(w, "\x1b["+p.sgr[role]+"m"+erase+text+"\x1b[m"+erase)
+}
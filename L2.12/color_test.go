@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGrepColors(t *testing.T) {
+	p := parseGrepColors("")
+	if p.sgr[colorSelectedMatch] != "01;31" || p.sgr[colorFileName] != "35" || p.sgr[colorSelectedLine] != "" {
+		t.Errorf("unexpected defaults: %+v", p)
+	}
+
+	p = parseGrepColors("mt=01;32:fn=34:sl=1:ln=:ne:bogus=7:rv")
+	if p.sgr[colorSelectedMatch] != "01;32" || p.sgr[colorContextMatch] != "01;32" {
+		t.Errorf("mt not applied: %+v", p)
+	}
+	if p.sgr[colorFileName] != "34" || p.sgr[colorSelectedLine] != "1" || p.sgr[colorLineNumber] != "" {
+		t.Errorf("fields not applied: %+v", p)
+	}
+	if !p.noErase || !p.reverse {
+		t.Errorf("capabilities not applied: %+v", p)
+	}
+	if line, _ := p.lineRoles(true, true); line != colorContextLine {
+		t.Errorf("rv with -v: selected lines use role %d", line)
+	}
+}
+
+func TestPaint(t *testing.T) {
+	var out strings.Builder
+	var none *palette
+	none.paint(&out, colorSelectedMatch, "plain ")
+	parseGrepColors("").paint(&out, colorSelectedMatch, "red")
+	parseGrepColors("ne").paint(&out, colorSelectedLine, " uncolored")
+
+	if want := "plain \x1b[01;31m\x1b[Kred\x1b[m\x1b[K uncolored"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestColorMode(t *testing.T) {
+	for value, want := range map[string]colorMode{"true": "auto", "tty": "auto", "always": "always", "force": "always", "never": "never", "none": "never"} {
+		var mode colorMode
+		if err := mode.Set(value); err != nil || mode != want {
+			t.Errorf("Set(%q) = %q, %v; want %q", value, mode, err, want)
+		}
+	}
+	var mode colorMode
+	if err := mode.Set("sometimes"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -2,12 +2,17 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Config struct {
@@ -19,18 +24,46 @@ type Config struct {
 	Invert      bool     // -v: invert match
 	FixedString bool     // -F: fixed string match
 	LineNumber  bool     // -n: show line numbers
+	ByteOffset  bool     // -b: show byte offset of each line
+	OnlyMatch   bool     // -o: print only the matched parts of each line
 	Pattern     string   // search pattern
 	Files       []string // input files
+
+	GroupSeparator   string // --group-separator: printed between context groups
+	NoGroupSeparator bool   // --no-group-separator: print nothing between groups
+
+	Color  colorMode // --color: when to highlight matches
+	Colors *palette  // highlighting in effect, nil for plain output
+
+	Parallel  int  // --parallel N: files searched at once, 0 for the number of CPUs
+	Unordered bool // --unordered: print files as they finish, not in argument order
+
+	Recursive    bool     // -r: search directories, following only command-line symlinks
+	Dereference  bool     // -R: search directories, following all symlinks
+	Include      globList // --include: search only files whose base name matches
+	Exclude      globList // --exclude: skip files whose base name matches
+	ExcludeDir   globList // --exclude-dir: skip directories whose base name matches
+	Text         bool     // -a: search binary files as text
+	IgnoreBinary bool     // -I: skip binary files without a notice
 }
 
 type Match struct {
 	LineNumber int
+	ByteOffset int64 // offset of the line start in the input
 	Content    string
 	IsMatch    bool // true if this line is an actual match, false if it's context
 }
 
+// Span is the byte range [Start, End) of a match within a line.
+type Span struct {
+	Start, End int
+}
+
+// Matcher finds the pattern in a line. Spans returns the non-empty,
+// non-overlapping matches from left to right.
 type Matcher interface {
 	Match(line string) bool
+	Spans(line string) []Span
 }
 
 type RegexMatcher struct {
@@ -41,6 +74,16 @@ func (rm *RegexMatcher) Match(line string) bool {
 	return rm.regex.MatchString(line)
 }
 
+func (rm *RegexMatcher) Spans(line string) []Span {
+	var spans []Span
+	for _, loc := range rm.regex.FindAllStringIndex(line, -1) {
+		if loc[1] > loc[0] {
+			spans = append(spans, Span{Start: loc[0], End: loc[1]})
+		}
+	}
+	return spans
+}
+
 type FixedMatcher struct {
 	pattern    string
 	ignoreCase bool
@@ -53,6 +96,63 @@ func (fm *FixedMatcher) Match(line string) bool {
 	return strings.Contains(line, fm.pattern)
 }
 
+func (fm *FixedMatcher) Spans(line string) []Span {
+	if fm.pattern == "" {
+		return nil
+	}
+	var spans []Span
+	for start := 0; start < len(line); {
+		if !fm.ignoreCase {
+			index := strings.Index(line[start:], fm.pattern)
+			if index < 0 {
+				break
+			}
+			spans = append(spans, Span{Start: start + index, End: start + index + len(fm.pattern)})
+			start += index + len(fm.pattern)
+			continue
+		}
+		// Case folding can change the byte length of a rune (K and the
+		// Kelvin sign), so the match length is taken from the line itself.
+		if n := foldPrefix(line[start:], fm.pattern); n > 0 {
+			spans = append(spans, Span{Start: start, End: start + n})
+			start += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(line[start:])
+		start += size
+	}
+	return spans
+}
+
+// foldPrefix returns the length of the prefix of text that equals prefix
+// under Unicode case folding, or 0 if text does not start with it.
+func foldPrefix(text, prefix string) int {
+	n := 0
+	for _, want := range prefix {
+		if n >= len(text) {
+			return 0
+		}
+		got, size := utf8.DecodeRuneInString(text[n:])
+		if !equalFold(got, want) {
+			return 0
+		}
+		n += size
+	}
+	return n
+}
+
+func equalFold(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
 func parseFlags() (*Config, error) {
 	config := &Config{}
 
@@ -64,6 +164,20 @@ func parseFlags() (*Config, error) {
 	flag.BoolVar(&config.Invert, "v", false, "invert match")
 	flag.BoolVar(&config.FixedString, "F", false, "interpret pattern as fixed string")
 	flag.BoolVar(&config.LineNumber, "n", false, "show line numbers")
+	flag.BoolVar(&config.ByteOffset, "b", false, "show the byte offset of each line")
+	flag.BoolVar(&config.OnlyMatch, "o", false, "print only the matched parts of each line")
+	flag.Var(&config.Color, "color", "highlight matches: auto, always or never (see GREP_COLORS)")
+	flag.BoolVar(&config.Recursive, "r", false, "search directories recursively")
+	flag.BoolVar(&config.Dereference, "R", false, "search directories recursively, following all symlinks")
+	flag.Var(&config.Include, "include", "search only files matching GLOB (may be repeated)")
+	flag.Var(&config.Exclude, "exclude", "skip files matching GLOB (may be repeated)")
+	flag.Var(&config.ExcludeDir, "exclude-dir", "skip directories matching GLOB (may be repeated)")
+	flag.BoolVar(&config.Text, "a", false, "process binary files as text")
+	flag.BoolVar(&config.IgnoreBinary, "I", false, "skip binary files")
+	flag.IntVar(&config.Parallel, "parallel", 0, "search N files at once (default: number of CPUs)")
+	flag.BoolVar(&config.Unordered, "unordered", false, "print results for each file as soon as it is searched")
+	flag.StringVar(&config.GroupSeparator, "group-separator", "--", "print SEP between groups of context lines")
+	flag.BoolVar(&config.NoGroupSeparator, "no-group-separator", false, "do not print a separator between groups of context lines")
 
 	flag.Parse()
 
@@ -75,12 +189,21 @@ func parseFlags() (*Config, error) {
 	config.Pattern = args[0]
 	config.Files = args[1:]
 
+	// -R implies -r
+	if config.Dereference {
+		config.Recursive = true
+	}
+
 	// -C flag sets both -A and -B
 	if config.Context > 0 {
 		config.After = config.Context
 		config.Before = config.Context
 	}
 
+	if config.Color.enabled(os.Stdout) {
+		config.Colors = parseGrepColors(os.Getenv("GREP_COLORS"))
+	}
+
 	return config, nil
 }
 
@@ -105,102 +228,259 @@ func createMatcher(config *Config) (Matcher, error) {
 	return &RegexMatcher{regex: regex}, nil
 }
 
+// processReader collects the lines searchReader would print. It is meant
+// for small inputs and tests; processFile streams instead.
 func processReader(reader io.Reader, matcher Matcher, config *Config) ([]Match, error) {
-	var lines []string
 	var matches []Match
+	_, err := searchReader(reader, matcher, config, func(match Match) error {
+		matches = append(matches, match)
+		return nil
+	}, nil)
+	return matches, err
+}
 
-	scanner := bufio.NewScanner(reader)
-	lineNum := 0
-
-	// Read all lines first
-	for scanner.Scan() {
-		lineNum++
-		lines = append(lines, scanner.Text())
+// searchReader reads lines one at a time and passes every line to print
+// (matches and their context) to emit as soon as it is known. Only the last
+// config.Before lines are kept for before-context, and after a match a
+// countdown of config.After lines follows, so memory use does not depend on
+// the input size. idle, if set, is called before every read that would have
+// to wait for more input, which lets the caller flush its output for
+// `tail -f | grep`. It returns the number of matching lines.
+//
+// Input whose first buffer contains a NUL byte is binary: unless config.Text
+// is set, its lines are not emitted and the search stops at the first match
+// with errBinaryMatch (with -c the matches are still counted). With -I binary
+// input is skipped.
+func searchReader(reader io.Reader, matcher Matcher, config *Config, emit func(Match) error, idle func() error) (int, error) {
+	input := bufio.NewReader(reader)
+	before := newLineRing(config.Before)
+	afterLeft := 0
+	count := 0
+	var offset int64
+
+	binary := !config.Text && isBinary(input)
+	if binary && config.IgnoreBinary {
+		return 0, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input: %v", err)
+	for lineNumber := 1; ; lineNumber++ {
+		if idle != nil && input.Buffered() == 0 {
+			if err := idle(); err != nil {
+				return count, err
+			}
+		}
+		line, err := input.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return count, fmt.Errorf("error reading input: %v", err)
+		}
+		if line == "" && err == io.EOF {
+			return count, nil
+		}
+		lineOffset := offset
+		offset += int64(len(line))
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		isMatch := matcher.Match(line) != config.Invert
+		switch {
+		case isMatch && binary:
+			count++
+			if !config.Count {
+				return count, errBinaryMatch
+			}
+		case isMatch:
+			count++
+			for _, context := range before.drain() {
+				if err := emit(context); err != nil {
+					return count, err
+				}
+			}
+			if err := emit(Match{LineNumber: lineNumber, ByteOffset: lineOffset, Content: line, IsMatch: true}); err != nil {
+				return count, err
+			}
+			afterLeft = config.After
+		case afterLeft > 0:
+			afterLeft--
+			if err := emit(Match{LineNumber: lineNumber, ByteOffset: lineOffset, Content: line}); err != nil {
+				return count, err
+			}
+		default:
+			before.push(Match{LineNumber: lineNumber, ByteOffset: lineOffset, Content: line})
+		}
+
+		if err == io.EOF {
+			return count, nil
+		}
 	}
+}
 
-	// Find matches
-	matchLines := make(map[int]bool)
-	contextLines := make(map[int]bool)
+// errBinaryMatch reports that a binary input matched; only a notice is printed.
+var errBinaryMatch = errors.New("binary file matches")
 
-	for i, line := range lines {
-		lineNumber := i + 1
-		isMatch := matcher.Match(line)
+// isBinary reports whether the first buffer of input contains a NUL byte.
+// It waits for a single read only, so streaming input is not held back.
+func isBinary(input *bufio.Reader) bool {
+	input.Peek(1)
+	head, _ := input.Peek(input.Buffered())
+	return bytes.IndexByte(head, 0) >= 0
+}
 
-		// Apply invert logic
-		if config.Invert {
-			isMatch = !isMatch
-		}
+// lineRing keeps the last few non-printed lines for -B context.
+type lineRing struct {
+	lines []Match
+	start int
+	size  int
+}
 
-		if isMatch {
-			matchLines[lineNumber] = true
+func newLineRing(capacity int) *lineRing {
+	return &lineRing{lines: make([]Match, max(capacity, 0))}
+}
 
-			// Add context lines
-			for j := max(1, lineNumber-config.Before); j <= min(len(lines), lineNumber+config.After); j++ {
-				contextLines[j] = true
-			}
-		}
+func (r *lineRing) push(line Match) {
+	if len(r.lines) == 0 {
+		return
+	}
+	if r.size < len(r.lines) {
+		r.lines[(r.start+r.size)%len(r.lines)] = line
+		r.size++
+		return
 	}
+	r.lines[r.start] = line
+	r.start = (r.start + 1) % len(r.lines)
+}
 
-	// Build result
-	for lineNumber := 1; lineNumber <= len(lines); lineNumber++ {
-		if contextLines[lineNumber] {
-			match := Match{
-				LineNumber: lineNumber,
-				Content:    lines[lineNumber-1],
-				IsMatch:    matchLines[lineNumber],
-			}
-			matches = append(matches, match)
-		}
+// drain returns the buffered lines oldest first and empties the ring.
+func (r *lineRing) drain() []Match {
+	drained := make([]Match, 0, r.size)
+	for i := 0; i < r.size; i++ {
+		drained = append(drained, r.lines[(r.start+i)%len(r.lines)])
 	}
+	r.start, r.size = 0, 0
+	return drained
+}
 
-	return matches, nil
+// printer writes lines for one input as searchReader finds them, using the
+// GNU grep prefixes: "file:12:" for matches and "file-12-" for context.
+type printer struct {
+	out      *bufio.Writer
+	groups   groupWriter // the destination of out, if it separates inputs itself
+	config   *Config
+	matcher  Matcher
+	filename string
+	lastLine int // number of the last printed line in this input, 0 if none
 }
 
-func formatOutput(matches []Match, config *Config, filename string) {
-	if config.Count {
-		count := 0
-		for _, match := range matches {
-			if match.IsMatch {
-				count++
-			}
+// groupWriter is an output shared by several inputs that writes the group
+// separator between them. startGroup is called before an input's first line.
+type groupWriter interface {
+	io.Writer
+	startGroup() error
+}
+
+func (p *printer) printLine(match Match) error {
+	if p.lastLine == 0 && p.groups != nil {
+		if err := p.out.Flush(); err != nil {
+			return err
 		}
-		if filename != "" {
-			fmt.Printf("%s:%d\n", filename, count)
-		} else {
-			fmt.Printf("%d\n", count)
+		if err := p.groups.startGroup(); err != nil {
+			return err
 		}
-		return
 	}
+	if p.config.OnlyMatch {
+		return p.printMatches(match)
+	}
+	if p.startsGroup(match) {
+		p.config.Colors.paint(p.out, colorSeparator, p.config.GroupSeparator)
+		p.out.WriteByte('\n')
+	}
+	p.lastLine = match.LineNumber
 
-	printed := make(map[int]bool)
+	p.printPrefix(match, match.ByteOffset)
+	p.printContent(match)
+	return p.out.WriteByte('\n')
+}
 
-	for _, match := range matches {
-		if printed[match.LineNumber] {
-			continue
+// printMatches implements -o: every match of a selected line goes on its own
+// line, and -b gives the offset of the match rather than of the line.
+// With -v selected lines have no matches, so nothing is printed.
+func (p *printer) printMatches(match Match) error {
+	if !match.IsMatch || p.config.Invert {
+		return nil
+	}
+	p.lastLine = match.LineNumber
+
+	colors := p.config.Colors
+	for _, span := range p.matcher.Spans(match.Content) {
+		p.printPrefix(match, match.ByteOffset+int64(span.Start))
+		colors.paint(p.out, colorSelectedMatch, match.Content[span.Start:span.End])
+		if err := p.out.WriteByte('\n'); err != nil {
+			return err
 		}
-		printed[match.LineNumber] = true
+	}
+	return nil
+}
 
-		var output strings.Builder
+func (p *printer) printPrefix(match Match, offset int64) {
+	colors := p.config.Colors
+	separator := "-"
+	if match.IsMatch {
+		separator = ":"
+	}
+	if p.filename != "" {
+		colors.paint(p.out, colorFileName, p.filename)
+		colors.paint(p.out, colorSeparator, separator)
+	}
+	if p.config.LineNumber {
+		colors.paint(p.out, colorLineNumber, strconv.Itoa(match.LineNumber))
+		colors.paint(p.out, colorSeparator, separator)
+	}
+	if p.config.ByteOffset {
+		colors.paint(p.out, colorByteOffset, strconv.FormatInt(offset, 10))
+		colors.paint(p.out, colorSeparator, separator)
+	}
+}
 
-		if filename != "" {
-			output.WriteString(filename)
-			output.WriteString(":")
-		}
+// printContent writes the line, highlighting matches when colors are on.
+// Matches are found in selected lines, or in context lines with -v.
+func (p *printer) printContent(match Match) {
+	colors := p.config.Colors
+	if colors == nil {
+		p.out.WriteString(match.Content)
+		return
+	}
 
-		if config.LineNumber {
-			output.WriteString(fmt.Sprintf("%d:", match.LineNumber))
-		}
+	lineColor, matchColor := colors.lineRoles(match.IsMatch, p.config.Invert)
+	var spans []Span
+	if match.IsMatch != p.config.Invert {
+		spans = p.matcher.Spans(match.Content)
+	}
+	pos := 0
+	for _, span := range spans {
+		colors.paint(p.out, lineColor, match.Content[pos:span.Start])
+		colors.paint(p.out, matchColor, match.Content[span.Start:span.End])
+		pos = span.End
+	}
+	colors.paint(p.out, lineColor, match.Content[pos:])
+}
+
+// startsGroup reports whether a group separator goes before match: every
+// group that does not directly continue the previous one in this input.
+// Separators between inputs are written by resultWriter.
+func (p *printer) startsGroup(match Match) bool {
+	return p.config.separatesGroups() && p.lastLine > 0 && match.LineNumber > p.lastLine+1
+}
 
-		output.WriteString(match.Content)
-		fmt.Println(output.String())
+func (p *printer) printCount(count int) error {
+	colors := p.config.Colors
+	if p.filename != "" {
+		colors.paint(p.out, colorFileName, p.filename)
+		colors.paint(p.out, colorSeparator, ":")
 	}
+	_, err := fmt.Fprintf(p.out, "%d\n", count)
+	return err
 }
 
-func processFile(filename string, matcher Matcher, config *Config) error {
+// processFile searches one input and writes its output to w.
+func processFile(filename string, matcher Matcher, config *Config, w io.Writer) error {
 	var reader io.Reader
 	var file *os.File
 	var err error
@@ -217,13 +497,38 @@ func processFile(filename string, matcher Matcher, config *Config) error {
 		reader = file
 	}
 
-	matches, err := processReader(reader, matcher, config)
+	out := &printer{out: bufio.NewWriter(w), config: config, matcher: matcher, filename: filename}
+	out.groups, _ = w.(groupWriter)
+	defer out.out.Flush()
+
+	emit := out.printLine
+	if config.Count {
+		emit = func(Match) error { return nil }
+	}
+	count, err := searchReader(reader, matcher, config, emit, out.out.Flush)
+	if errors.Is(err, errBinaryMatch) {
+		name := filename
+		if name == "" {
+			name = "(standard input)"
+		}
+		fmt.Fprintf(out.out, "Binary file %s matches\n", name)
+		return out.out.Flush()
+	}
 	if err != nil {
 		return err
 	}
+	if config.Count {
+		if err := out.printCount(count); err != nil {
+			return err
+		}
+	}
+	return out.out.Flush()
+}
 
-	formatOutput(matches, config, filename)
-	return nil
+// separatesGroups reports whether "--" style separators are printed.
+// With -o context lines are not printed, so there are no groups either.
+func (c *Config) separatesGroups() bool {
+	return (c.Before > 0 || c.After > 0) && !c.NoGroupSeparator && !c.OnlyMatch
 }
 
 func main() {
@@ -239,16 +544,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	if len(config.Files) == 0 {
-		config.Files = []string{"-"}
+	files, walkErrors := collectFiles(config)
+	hasErrors := len(walkErrors) > 0
+	for _, err := range walkErrors {
+		fmt.Fprintf(os.Stderr, "grep: %v\n", err)
 	}
 
-	hasErrors := false
-	for _, filename := range config.Files {
-		if err := processFile(filename, matcher, config); err != nil {
-			fmt.Fprintf(os.Stderr, "grep: %v\n", err)
-			hasErrors = true
-		}
+	if !searchFiles(files, matcher, config, os.Stdout, os.Stderr) {
+		hasErrors = true
 	}
 
 	if hasErrors {
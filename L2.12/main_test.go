@@ -1,6 +1,10 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -144,3 +148,214 @@ func TestMultipleMatchesWithOverlap(t *testing.T) {
 		t.Fatalf("expected 5 lines due to overlapping contexts, got %d", len(matches))
 	}
 }
+
+func TestBeforeContextKeepsOnlyLastLines(t *testing.T) {
+	input := "a\nb\nc\nd\nfoo\ne\nf\ng\nfoo\n"
+	config := &Config{Pattern: "foo", Before: 2, After: 1}
+	matcher, _ := createMatcher(config)
+
+	matches, _ := processReader(strings.NewReader(input), matcher, config)
+
+	expected := []string{"c", "d", "foo", "e", "f", "g", "foo"}
+	if len(matches) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(matches))
+	}
+	for i, m := range matches {
+		if m.Content != expected[i] {
+			t.Errorf("line %d: expected %q, got %q", i, expected[i], m.Content)
+		}
+	}
+	if matches[0].LineNumber != 3 || matches[6].LineNumber != 9 {
+		t.Errorf("unexpected line numbers: %d, %d", matches[0].LineNumber, matches[6].LineNumber)
+	}
+}
+
+func TestSearchReaderStreams(t *testing.T) {
+	reader, writer := io.Pipe()
+	config := &Config{Pattern: "ERROR"}
+	matcher, _ := createMatcher(config)
+
+	found := make(chan Match)
+	done := make(chan error)
+	go func() {
+		_, err := searchReader(reader, matcher, config, func(m Match) error {
+			found <- m
+			return nil
+		}, nil)
+		done <- err
+	}()
+
+	// The match must be reported while the input is still open.
+	io.WriteString(writer, "ok\nERROR one\n")
+	if m := <-found; m.Content != "ERROR one" {
+		t.Errorf("expected 'ERROR one', got %q", m.Content)
+	}
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	long := strings.Repeat("x", 100*1024) + "foo"
+	config := &Config{Pattern: "foo"}
+	matcher, _ := createMatcher(config)
+
+	matches, err := processReader(strings.NewReader(long+"\nbar"), matcher, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Content != long {
+		t.Fatalf("expected the long line to match")
+	}
+}
+
+func printAll(t *testing.T, input string, config *Config, filename string) string {
+	t.Helper()
+	matcher, _ := createMatcher(config)
+	var output bytes.Buffer
+	out := &printer{out: bufio.NewWriter(&output), config: config, matcher: matcher, filename: filename}
+	if _, err := searchReader(strings.NewReader(input), matcher, config, out.printLine, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.out.Flush()
+	return output.String()
+}
+
+func TestGroupSeparators(t *testing.T) {
+	input := "a\nfoo\nb\nc\nd\nfoo\ne\n"
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"no context", Config{Pattern: "foo", GroupSeparator: "--"}, "foo\nfoo\n"},
+		{"after context", Config{Pattern: "foo", After: 1, GroupSeparator: "--"}, "foo\nb\n--\nfoo\ne\n"},
+		{"adjacent groups merge", Config{Pattern: "foo", Before: 2, After: 1, GroupSeparator: "--"}, "a\nfoo\nb\nc\nd\nfoo\ne\n"},
+		{"custom separator", Config{Pattern: "foo", After: 1, GroupSeparator: "=="}, "foo\nb\n==\nfoo\ne\n"},
+		{"no separator", Config{Pattern: "foo", After: 1, GroupSeparator: "--", NoGroupSeparator: true}, "foo\nb\nfoo\ne\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := printAll(t, input, &tt.config, ""); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatchAndContextPrefixes(t *testing.T) {
+	input := "a\nfoo\nb\n"
+	config := &Config{Pattern: "foo", Before: 1, After: 1, LineNumber: true, ByteOffset: true}
+
+	expected := "f.txt-1-0-a\nf.txt:2:2:foo\nf.txt-3-6-b\n"
+	if got := printAll(t, input, config, "f.txt"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestByteOffsetsWithCRLF(t *testing.T) {
+	config := &Config{Pattern: "x", ByteOffset: true}
+
+	expected := "0:x1\n8:x2\n"
+	if got := printAll(t, "x1\r\nyy\r\nx2\r\n", config, ""); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestMatcherSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		line     string
+		expected []Span
+	}{
+		{"fixed", Config{Pattern: "ab", FixedString: true}, "xabyabab", []Span{{1, 3}, {4, 6}, {6, 8}}},
+		{"fixed no overlap", Config{Pattern: "aa", FixedString: true}, "aaa", []Span{{0, 2}}},
+		{"fixed ignore case", Config{Pattern: "foo", FixedString: true, IgnoreCase: true}, "Foo fOO", []Span{{0, 3}, {4, 7}}},
+		{"fixed fold changes length", Config{Pattern: "k", FixedString: true, IgnoreCase: true}, "\u212a-k", []Span{{0, 3}, {4, 5}}},
+		{"fixed empty pattern", Config{Pattern: "", FixedString: true}, "abc", nil},
+		{"regex", Config{Pattern: "[0-9]+"}, "a12b345", []Span{{1, 3}, {4, 7}}},
+		{"regex skips empty matches", Config{Pattern: "x*"}, "axxb", []Span{{1, 3}}},
+		{"regex ignore case", Config{Pattern: "é", IgnoreCase: true}, "É", []Span{{0, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := createMatcher(&tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if spans := matcher.Spans(tt.line); !reflect.DeepEqual(spans, tt.expected) {
+				t.Errorf("Spans(%q) = %v, want %v", tt.line, spans, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOnlyMatching(t *testing.T) {
+	input := "a1b22\nnone\n333\n"
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{"each match on its own line", Config{Pattern: "[0-9]+", OnlyMatch: true}, "1\n22\n333\n"},
+		{"line numbers and match offsets", Config{Pattern: "[0-9]+", OnlyMatch: true, LineNumber: true, ByteOffset: true}, "1:1:1\n1:3:22\n3:11:333\n"},
+		{"context is not printed", Config{Pattern: "333", OnlyMatch: true, Before: 1, GroupSeparator: "--"}, "333\n"},
+		{"invert prints nothing", Config{Pattern: "none", OnlyMatch: true, Invert: true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := printAll(t, input, &tt.config, ""); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestColoredOutput(t *testing.T) {
+	input := "foo bar foo\nbaz\n"
+	colors := parseGrepColors("ne")
+
+	tests := []struct {
+		name     string
+		config   Config
+		filename string
+		expected string
+	}{
+		{
+			"matches and prefixes",
+			Config{Pattern: "foo", LineNumber: true, Colors: colors}, "f",
+			"\x1b[35mf\x1b[m\x1b[36m:\x1b[m\x1b[32m1\x1b[m\x1b[36m:\x1b[m" +
+				"\x1b[01;31mfoo\x1b[m bar \x1b[01;31mfoo\x1b[m\n",
+		},
+		{
+			"context lines and separators",
+			Config{Pattern: "baz", Before: 1, Colors: colors}, "",
+			"foo bar foo\n\x1b[01;31mbaz\x1b[m\n",
+		},
+		{
+			"invert highlights context",
+			Config{Pattern: "baz", Invert: true, After: 1, Colors: colors}, "",
+			"foo bar foo\n\x1b[01;31mbaz\x1b[m\n",
+		},
+		{
+			"only matching",
+			Config{Pattern: "o+", OnlyMatch: true, Colors: colors}, "",
+			"\x1b[01;31moo\x1b[m\n\x1b[01;31moo\x1b[m\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := printAll(t, input, &tt.config, tt.filename); got != tt.expected {
+				t.Errorf("got %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// spoolLimit caps the output kept in memory for a file that is searched
+// ahead of its turn; once it is reached the file's worker waits.
+const spoolLimit = 256 << 10
+
+// job is one input of searchFiles.
+type job struct {
+	spool *spool
+	done  chan struct{} // closed when the search has finished
+	err   error
+}
+
+// searchFiles searches files with a pool of workers and reports whether
+// every input was searched without errors. Output for each file is written
+// as one unit, in argument order or, with --unordered, in completion order.
+// The file whose turn it is streams straight to stdout, so `tail -f log |
+// grep ERROR - other.log` prints as lines arrive, with or without
+// --unordered; the others are spooled in memory up to spoolLimit each.
+func searchFiles(files []string, matcher Matcher, config *Config, stdout, stderr io.Writer) bool {
+	if len(files) == 1 {
+		if err := processFile(files[0], matcher, config, stdout); err != nil {
+			fmt.Fprintf(stderr, "grep: %v\n", err)
+			return false
+		}
+		return true
+	}
+
+	workers := config.Parallel
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	w := &resultWriter{config: config, stdout: stdout, stderr: stderr}
+	var queue *completionQueue
+	if config.Unordered {
+		queue = newCompletionQueue(len(files))
+	}
+	jobs := make([]*job, len(files))
+	for i := range jobs {
+		jobs[i] = &job{spool: newSpool(w), done: make(chan struct{})}
+		if queue != nil {
+			jobs[i].spool.full = func() { queue.events <- jobEvent{job: jobs[i], state: jobFull} }
+		}
+	}
+
+	// window limits the searches in flight, including finished ones whose
+	// output waits for its turn.
+	window := make(chan struct{}, workers)
+	go func() {
+		for i, filename := range files {
+			window <- struct{}{}
+			if queue != nil {
+				queue.events <- jobEvent{job: jobs[i], state: jobStarted}
+			}
+			go func(job *job, filename string) {
+				job.err = processFile(filename, matcher, config, job.spool)
+				close(job.done)
+				if queue != nil {
+					queue.events <- jobEvent{job: job, state: jobDone}
+				}
+			}(jobs[i], filename)
+		}
+	}()
+
+	for i := range jobs {
+		job := jobs[i]
+		if queue != nil {
+			job = queue.next()
+		}
+		job.spool.promote(stdout)
+		<-job.done
+		if job.err != nil {
+			fmt.Fprintf(stderr, "grep: %v\n", job.err)
+			w.failed = true
+		}
+		<-window
+	}
+	return !w.failed
+}
+
+// resultWriter tracks what searchFiles has printed so far to write the
+// group separators that GNU grep prints between files when context is on.
+type resultWriter struct {
+	config  *Config
+	stdout  io.Writer
+	stderr  io.Writer
+	grouped bool // a group of lines was written already
+	failed  bool
+}
+
+// startGroup is called before the first line of a file is written.
+func (w *resultWriter) startGroup() error {
+	if w.grouped && w.config.separatesGroups() {
+		w.config.Colors.paint(w.stdout, colorSeparator, w.config.GroupSeparator)
+		if _, err := fmt.Fprintln(w.stdout); err != nil {
+			return err
+		}
+	}
+	w.grouped = true
+	return nil
+}
+
+// spool receives the output of one file. Until promote is called the output
+// is kept in memory, and a write that would take it over spoolLimit waits;
+// afterwards everything goes straight to stdout.
+type spool struct {
+	mu       sync.Mutex
+	promoted *sync.Cond
+	results  *resultWriter
+	out      io.Writer // stdout after promote, nil before
+	buffer   bytes.Buffer
+	grouped  bool   // startGroup was called before promote
+	full     func() // called once when a write first has to wait, may be nil
+}
+
+func newSpool(results *resultWriter) *spool {
+	s := &spool{results: results}
+	s.promoted = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *spool) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.out == nil && s.buffer.Len() > 0 && s.buffer.Len()+len(p) > spoolLimit {
+		if s.full != nil {
+			s.full()
+			s.full = nil
+		}
+		s.promoted.Wait()
+	}
+	if s.out != nil {
+		return s.out.Write(p)
+	}
+	return s.buffer.Write(p)
+}
+
+func (s *spool) startGroup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.out == nil {
+		s.grouped = true // nothing was written yet, the separator goes first
+		return nil
+	}
+	return s.results.startGroup()
+}
+
+// promote writes the spooled output to out and switches the spool to
+// writing straight through. It is called when the file's turn comes.
+func (s *spool) promote(out io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.grouped {
+		s.results.startGroup()
+	}
+	out.Write(s.buffer.Bytes())
+	s.buffer = bytes.Buffer{}
+	s.out = out
+	s.promoted.Broadcast()
+}
+
+// jobEvent reports that a job started, that its spool is full or that it
+// finished.
+type jobEvent struct {
+	job   *job
+	state jobState
+}
+
+type jobState int
+
+const (
+	jobStarted jobState = iota
+	jobFull
+	jobDone
+)
+
+// completionQueue picks the order of output with --unordered: finished
+// files in completion order; when none has finished, a file whose worker
+// waits on a full spool; and when none waits either, the oldest file still
+// being searched, so that a slow input such as a pipe streams.
+type completionQueue struct {
+	events   chan jobEvent
+	printed  map[*job]bool
+	finished []*job
+	waiting  []*job
+	running  []*job // in launch order
+}
+
+func newCompletionQueue(jobs int) *completionQueue {
+	// Every job sends at most three events, so senders never block.
+	return &completionQueue{events: make(chan jobEvent, 3*jobs), printed: make(map[*job]bool)}
+}
+
+func (q *completionQueue) next() *job {
+	for {
+		select {
+		case event := <-q.events:
+			q.add(event)
+			continue
+		default:
+		}
+		if job := q.pop(); job != nil {
+			return job
+		}
+		q.add(<-q.events)
+	}
+}
+
+func (q *completionQueue) add(event jobEvent) {
+	switch {
+	case q.printed[event.job]:
+	case event.state == jobStarted:
+		q.running = append(q.running, event.job)
+	case event.state == jobFull:
+		q.waiting = append(q.waiting, event.job)
+	default:
+		q.finished = append(q.finished, event.job)
+	}
+}
+
+func (q *completionQueue) pop() *job {
+	for _, list := range []*[]*job{&q.finished, &q.waiting, &q.running} {
+		for len(*list) > 0 {
+			job := (*list)[0]
+			*list = (*list)[1:]
+			if !q.printed[job] {
+				q.printed[job] = true
+				return job
+			}
+		}
+	}
+	return nil
+}
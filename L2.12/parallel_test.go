@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFiles creates count files, each with lines "<name> line <n>".
+func writeFiles(t *testing.T, count, lines int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	files := make([]string, count)
+	for i := range files {
+		files[i] = filepath.Join(dir, fmt.Sprintf("f%03d", i))
+		var content strings.Builder
+		for n := 1; n <= lines; n++ {
+			fmt.Fprintf(&content, "f%03d line %d\n", i, n)
+		}
+		if err := os.WriteFile(files[i], []byte(content.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+func TestSearchFilesOrdered(t *testing.T) {
+	files := writeFiles(t, 50, 200)
+	matcher := &RegexMatcher{regexp.MustCompile("line 1")}
+
+	var want bytes.Buffer
+	for _, file := range files {
+		if err := processFile(file, matcher, &Config{}, &want); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if !searchFiles(files, matcher, &Config{Parallel: 4}, &stdout, &stderr) {
+		t.Fatalf("unexpected errors: %s", stderr.String())
+	}
+	if stdout.String() != want.String() {
+		t.Error("parallel output differs from sequential output")
+	}
+}
+
+func TestSearchFilesUnordered(t *testing.T) {
+	files := writeFiles(t, 30, 100)
+	matcher := &FixedMatcher{pattern: "line"}
+
+	var stdout, stderr bytes.Buffer
+	if !searchFiles(files, matcher, &Config{Parallel: 8, Unordered: true}, &stdout, &stderr) {
+		t.Fatalf("unexpected errors: %s", stderr.String())
+	}
+
+	// Every file's lines must form one contiguous block.
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 30*100 {
+		t.Fatalf("got %d lines, want %d", len(lines), 30*100)
+	}
+	var seen []string
+	for i := 0; i < len(lines); i += 100 {
+		file, _, _ := strings.Cut(lines[i], ":")
+		for n, line := range lines[i : i+100] {
+			if want := fmt.Sprintf("%s:%s line %d", file, filepath.Base(file), n+1); line != want {
+				t.Fatalf("output interleaved: got %q, want %q", line, want)
+			}
+		}
+		seen = append(seen, file)
+	}
+	sort.Strings(seen)
+	if strings.Join(seen, " ") != strings.Join(files, " ") {
+		t.Errorf("files printed: %v", seen)
+	}
+}
+
+func TestSearchFilesSeparatorsAndErrors(t *testing.T) {
+	files := writeFiles(t, 2, 3)
+	files = []string{files[0], filepath.Join(t.TempDir(), "missing"), files[1]}
+	matcher := &FixedMatcher{pattern: "line 2"}
+	config := &Config{Before: 1, GroupSeparator: "--", Parallel: 2}
+
+	var stdout, stderr bytes.Buffer
+	if searchFiles(files, matcher, config, &stdout, &stderr) {
+		t.Error("expected an error for the missing file")
+	}
+	want := fmt.Sprintf("%s-f000 line 1\n%s:f000 line 2\n--\n%s-f001 line 1\n%s:f001 line 2\n",
+		files[0], files[0], files[2], files[2])
+	if stdout.String() != want {
+		t.Errorf("stdout:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+	if !strings.Contains(stderr.String(), "missing") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestSearchFilesStreamsHeadFile(t *testing.T) {
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+
+	other := writeFiles(t, 1, 3)[0]
+	stdout, output := io.Pipe()
+	done := make(chan bool, 1)
+	go func() {
+		done <- searchFiles([]string{"-", other}, &FixedMatcher{pattern: "line"}, &Config{Parallel: 2}, output, io.Discard)
+		output.Close()
+	}()
+
+	// The head file is still open, but its lines must arrive as they are written.
+	lines := bufio.NewReader(stdout)
+	for i := 1; i <= 2; i++ {
+		fmt.Fprintf(input, "line %d\n", i)
+		got, err := readWithTimeout(t, lines)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("line %d\n", i); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+	input.Close()
+
+	rest, err := io.ReadAll(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("%s:f000 line 1\n%s:f000 line 2\n%s:f000 line 3\n", other, other, other); string(rest) != want {
+		t.Errorf("got %q, want %q", rest, want)
+	}
+	if !<-done {
+		t.Error("unexpected errors")
+	}
+}
+
+func TestSearchFilesUnorderedStreamsRunningFile(t *testing.T) {
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+
+	other := writeFiles(t, 1, 3)[0]
+	stdout, output := io.Pipe()
+	done := make(chan bool, 1)
+	go func() {
+		config := &Config{Parallel: 2, Unordered: true}
+		done <- searchFiles([]string{"-", other}, &FixedMatcher{pattern: "line"}, config, output, io.Discard)
+		output.Close()
+	}()
+
+	// Nothing has finished and no spool is full, yet the lines of stdin must
+	// arrive as they are written. The other file may be printed before it.
+	lines := bufio.NewReader(stdout)
+	var otherLines []string
+	for i := 1; i <= 2; i++ {
+		fmt.Fprintf(input, "line %d\n", i)
+		for {
+			got, err := readWithTimeout(t, lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(got, other+":") {
+				otherLines = append(otherLines, got)
+				continue
+			}
+			if want := fmt.Sprintf("line %d\n", i); got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+			break
+		}
+	}
+	input.Close()
+
+	rest, err := io.ReadAll(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%s:f000 line 1\n%s:f000 line 2\n%s:f000 line 3\n", other, other, other)
+	if got := strings.Join(otherLines, "") + string(rest); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !<-done {
+		t.Error("unexpected errors")
+	}
+}
+
+func readWithTimeout(t *testing.T, reader *bufio.Reader) (string, error) {
+	t.Helper()
+	type result struct {
+		line string
+		err  error
+	}
+	read := make(chan result, 1)
+	go func() {
+		line, err := reader.ReadString('\n')
+		read <- result{line, err}
+	}()
+	select {
+	case r := <-read:
+		return r.line, r.err
+	case <-time.After(5 * time.Second):
+		t.Fatal("output of the head file was not streamed")
+		return "", nil
+	}
+}
+
+func TestSpoolLimit(t *testing.T) {
+	s := newSpool(&resultWriter{config: &Config{}})
+	chunk := bytes.Repeat([]byte("x"), spoolLimit/4)
+
+	written := make(chan struct{})
+	go func() {
+		for i := 0; i < 8; i++ {
+			s.Write(chunk)
+		}
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("writes beyond spoolLimit did not wait for promote")
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.mu.Lock()
+	if s.buffer.Len() > spoolLimit {
+		t.Errorf("spooled %d bytes, limit is %d", s.buffer.Len(), spoolLimit)
+	}
+	s.mu.Unlock()
+
+	var out bytes.Buffer
+	s.promote(&out)
+	<-written
+	if out.Len() != 8*len(chunk) {
+		t.Errorf("got %d bytes after promote, want %d", out.Len(), 8*len(chunk))
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// globList collects repeated --include/--exclude/--exclude-dir patterns.
+type globList []string
+
+func (g *globList) String() string { return strings.Join(*g, ",") }
+
+func (g *globList) Set(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob %q: %v", pattern, err)
+	}
+	*g = append(*g, pattern)
+	return nil
+}
+
+// matches reports whether the base name of path matches any pattern.
+func (g globList) matches(path string) bool {
+	name := filepath.Base(path)
+	for _, pattern := range g {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// collectFiles expands the command-line operands into the list of inputs
+// to search, in the order grep prints them. Without operands it searches
+// stdin, or the working directory with -r. Directories are walked only with
+// -r/-R, in name order. Symlinks given on the command line are always
+// followed; symlinks found while walking only with -R. Errors for single
+// operands are returned without stopping the walk.
+func collectFiles(config *Config) ([]string, []error) {
+	w := &walker{config: config}
+
+	if len(config.Files) == 0 {
+		if !config.Recursive {
+			return []string{"-"}, nil
+		}
+		// Like GNU grep, print paths under the implicit "." without a prefix.
+		w.walkDir(".", "", nil)
+		return w.files, w.errors
+	}
+
+	for _, operand := range config.Files {
+		w.visit(operand, true, nil)
+	}
+	return w.files, w.errors
+}
+
+type walker struct {
+	config *Config
+	files  []string
+	errors []error
+}
+
+// visit adds path, or the files below it, to the result. ancestors are the
+// directories being walked above path, used to detect symlink loops.
+func (w *walker) visit(path string, commandLine bool, ancestors []os.FileInfo) {
+	if path == "-" {
+		w.files = append(w.files, path)
+		return
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		w.errors = append(w.errors, err)
+		return
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if !commandLine && !w.config.Dereference {
+			return
+		}
+		if info, err = os.Stat(path); err != nil {
+			w.errors = append(w.errors, err)
+			return
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		if !w.config.Recursive {
+			w.errors = append(w.errors, fmt.Errorf("%s: Is a directory", path))
+			return
+		}
+		if w.config.ExcludeDir.matches(path) {
+			return
+		}
+		for _, ancestor := range ancestors {
+			if os.SameFile(ancestor, info) {
+				w.errors = append(w.errors, fmt.Errorf("%s: warning: recursive directory loop", path))
+				return
+			}
+		}
+		prefix := path
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		w.walkDir(path, prefix, append(ancestors, info))
+	case !commandLine && !info.Mode().IsRegular():
+		// Devices, FIFOs and sockets are skipped while walking.
+	case !w.included(path):
+	default:
+		w.files = append(w.files, path)
+	}
+}
+
+// walkDir visits the entries of dir, naming them prefix+name.
+func (w *walker) walkDir(dir, prefix string, ancestors []os.FileInfo) {
+	if ancestors == nil {
+		info, err := os.Stat(dir)
+		if err != nil {
+			w.errors = append(w.errors, err)
+			return
+		}
+		ancestors = []os.FileInfo{info}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.errors = append(w.errors, err)
+		return
+	}
+	for _, entry := range entries {
+		w.visit(prefix+entry.Name(), false, ancestors)
+	}
+}
+
+// included applies --include and --exclude to a file; --exclude wins.
+func (w *walker) included(path string) bool {
+	if w.config.Exclude.matches(path) {
+		return false
+	}
+	return len(w.config.Include) == 0 || w.config.Include.matches(path)
+}
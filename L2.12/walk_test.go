@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func makeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"src/sub", "vendor", "other"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"src/a.go", "src/b.txt", "src/sub/c.go", "vendor/v.go", "other/o.go"} {
+		if err := os.WriteFile(filepath.Join(root, file), []byte("foo\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "other"), filepath.Join(root, "src", "link")); err != nil {
+		t.Skip("symlinks are not supported:", err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "src", "sub", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func relativeFiles(root string, files []string) []string {
+	relative := make([]string, len(files))
+	for i, file := range files {
+		relative[i] = strings.TrimPrefix(file, root+"/")
+	}
+	return relative
+}
+
+func TestCollectFiles(t *testing.T) {
+	root := makeTree(t)
+
+	tests := []struct {
+		name       string
+		config     Config
+		expected   []string
+		wantErrors int
+	}{
+		{
+			name:     "recursive skips symlinks found while walking",
+			config:   Config{Recursive: true},
+			expected: []string{"other/o.go", "src/a.go", "src/b.txt", "src/sub/c.go", "vendor/v.go"},
+		},
+		{
+			name:     "include and exclude-dir",
+			config:   Config{Recursive: true, Include: globList{"*.go"}, ExcludeDir: globList{"vendor"}},
+			expected: []string{"other/o.go", "src/a.go", "src/sub/c.go"},
+		},
+		{
+			name:     "exclude wins over include",
+			config:   Config{Recursive: true, Include: globList{"*.go"}, Exclude: globList{"a.*"}, ExcludeDir: globList{"vendor", "other"}},
+			expected: []string{"src/sub/c.go"},
+		},
+		{
+			name:       "dereference follows links and stops at loops",
+			config:     Config{Recursive: true, Dereference: true, ExcludeDir: globList{"vendor", "other"}},
+			expected:   []string{"src/a.go", "src/b.txt", "src/link/o.go", "src/sub/c.go"},
+			wantErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Files = []string{root}
+			files, errs := collectFiles(&tt.config)
+			if got := relativeFiles(root, files); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if len(errs) != tt.wantErrors {
+				t.Errorf("expected %d errors, got %v", tt.wantErrors, errs)
+			}
+		})
+	}
+}
+
+func TestCollectFilesOperands(t *testing.T) {
+	root := makeTree(t)
+
+	// A symlink given on the command line is followed even with -r.
+	config := &Config{Recursive: true, Files: []string{filepath.Join(root, "src", "link")}}
+	files, errs := collectFiles(config)
+	if len(errs) != 0 || len(files) != 1 || filepath.Base(files[0]) != "o.go" {
+		t.Errorf("expected link/o.go, got %q (errors %v)", files, errs)
+	}
+
+	// --exclude-dir applies to directory operands too, like --exclude to files.
+	config = &Config{Recursive: true, ExcludeDir: globList{"vendor"}, Files: []string{filepath.Join(root, "vendor"), filepath.Join(root, "other")}}
+	files, errs = collectFiles(config)
+	if len(errs) != 0 || !reflect.DeepEqual(relativeFiles(root, files), []string{"other/o.go"}) {
+		t.Errorf("expected only other/o.go, got %q (errors %v)", files, errs)
+	}
+
+	// Without -r a directory operand is an error.
+	config = &Config{Files: []string{root, "-"}}
+	files, errs = collectFiles(config)
+	if len(errs) != 1 || !reflect.DeepEqual(files, []string{"-"}) {
+		t.Errorf("expected a directory error and stdin, got %q (errors %v)", files, errs)
+	}
+}
+
+func TestBinaryInput(t *testing.T) {
+	input := "foo\x00bar\nfoo\n"
+	config := &Config{Pattern: "foo"}
+	matcher, _ := createMatcher(config)
+
+	if _, err := processReader(strings.NewReader(input), matcher, config); err != errBinaryMatch {
+		t.Fatalf("expected errBinaryMatch, got %v", err)
+	}
+
+	config.IgnoreBinary = true
+	if matches, err := processReader(strings.NewReader(input), matcher, config); err != nil || len(matches) != 0 {
+		t.Fatalf("expected binary input to be skipped with -I, got %v, %v", matches, err)
+	}
+
+	config = &Config{Pattern: "foo", Text: true}
+	if matches, err := processReader(strings.NewReader(input), matcher, config); err != nil || len(matches) != 2 {
+		t.Fatalf("expected 2 matches with -a, got %v, %v", matches, err)
+	}
+
+	config = &Config{Pattern: "foo", Count: true}
+	if count, err := searchReader(strings.NewReader(input), matcher, config, func(Match) error { return nil }, nil); err != nil || count != 2 {
+		t.Fatalf("expected -c to count 2 binary matches, got %d, %v", count, err)
+	}
+}
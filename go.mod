@@ -6,6 +6,7 @@ require (
 	github.com/beevik/ntp v1.4.3
 	github.com/spf13/pflag v1.0.7
 	golang.org/x/net v0.25.0
+	golang.org/x/text v0.22.0
 )
 
 require golang.org/x/sys v0.20.0 // indirect